@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/baotoken"
+	"casdoor-casbin-openbao/internal/bulk"
 	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/checkout"
 	"casdoor-casbin-openbao/internal/config"
 	"casdoor-casbin-openbao/internal/database"
+	"casdoor-casbin-openbao/internal/fieldcrypt"
 	"casdoor-casbin-openbao/internal/handler"
+	"casdoor-casbin-openbao/internal/idempotency"
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/loginlimit"
+	"casdoor-casbin-openbao/internal/metrics"
+	"casdoor-casbin-openbao/internal/mtls"
+	"casdoor-casbin-openbao/internal/openbao"
+	"casdoor-casbin-openbao/internal/repository"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -25,6 +38,12 @@ func main() {
 
 	// Initialize config
 	config.Init()
+
+	// Resolve bao:// references in the configuration; a secret that cannot
+	// be resolved stops the server here
+	if err := config.LoadSecrets(context.Background()); err != nil {
+		log.Fatal("Failed to load configuration secrets: ", err)
+	}
 	cfg := config.GetConfig()
 
 	// Initialize database
@@ -32,6 +51,22 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// Server certificate from OpenBao PKI for mTLS
+	tlsServer, err := newTLSServer(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize mTLS: ", err)
+	}
+
+	// Encrypt selected fields with OpenBao transit (before any table access)
+	if err := initFieldEncryption(cfg); err != nil {
+		log.Fatal("Failed to initialize field encryption: ", err)
+	}
+
+	// Create or update application tables
+	if err := database.Migrate(); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
 	// Initialize Casbin
 	if err := casbin.InitEnforcer(); err != nil {
 		log.Fatal("Failed to initialize Casbin:", err)
@@ -39,14 +74,21 @@ func main() {
 
 	// Create Echo instance
 	e := echo.New()
+	// Client IPs feed the login limits; X-Forwarded-For is only trusted
+	// behind a proxy, otherwise anyone could pick their own IP
+	if cfg.Server.TrustProxyHeaders {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
 
 	// Middleware
-	e.Use(middleware.Logger())
+	e.Use(middleware.LoggerWithConfig(requestLogConfig()))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, auth.HeaderAPIKey, idempotency.HeaderIdempotencyKey},
 	}))
 
 	// Health check
@@ -57,15 +99,55 @@ func main() {
 		})
 	})
 
+	// Prometheus metrics (database credential leases and rotations)
+	e.GET("/metrics", metrics.Handler)
+
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler()
+	tokenIssuer, err := newTokenIssuer(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure OpenBao user tokens: ", err)
+	}
+	loginLimiter, err := newLoginLimiter(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure login limits: ", err)
+	}
+	authHandler := handler.NewAuthHandler(tokenIssuer, loginLimiter)
+	openBaoTokenHandler := handler.NewOpenBaoTokenHandler(tokenIssuer)
 	userHandler := handler.NewUserHandler()
 	adminHandler := handler.NewAdminHandler()
 	debugHandler := handler.NewDebugHandler()
 	fixHandler := handler.NewFixHandler()
 	microsoftHandler := handler.NewMicrosoftHandler()
-	transactionHandler := handler.NewTransactionHandler()
-	orderHandler := handler.NewOrderHandler()
+	thresholds, err := ledger.ParseThresholds(cfg.Approval.Thresholds)
+	if err != nil {
+		log.Fatal("Invalid APPROVAL_THRESHOLDS:", err)
+	}
+	transactor := repository.NewPostgresTransactor(database.GetDB())
+	transactionRepo := repository.NewPostgresTransactionRepository(database.GetDB())
+	orderRepo := repository.NewPostgresOrderRepository(database.GetDB())
+	ledgerService := ledger.NewService(
+		transactor,
+		repository.NewPostgresAccountRepository(database.GetDB()),
+		transactionRepo,
+		ledger.ApprovalPolicy{Thresholds: thresholds, Roles: casbin.GetRolesForUser},
+	)
+	transactionHandler := handler.NewTransactionHandler(transactionRepo, ledgerService)
+	accountHandler := handler.NewAccountHandler(ledgerService)
+	productRepo := repository.NewPostgresProductRepository(database.GetDB())
+	productHandler := handler.NewProductHandler(productRepo)
+	reportHandler := handler.NewReportHandler(orderRepo, transactionRepo)
+	bulkHandler := handler.NewBulkHandler(orderRepo, transactionRepo, bulk.NewImporter(transactor, orderRepo, transactionRepo, ledgerService))
+	sharingHandler := handler.NewSharingHandler(orderRepo, transactionRepo)
+	encryptionHandler := handler.NewEncryptionHandler(database.GetDB())
+	orderHandler := handler.NewOrderHandler(orderRepo, checkout.NewService(transactor, orderRepo, productRepo, transactionRepo, ledgerService))
+	serviceAccountRepo := repository.NewPostgresServiceAccountRepository(database.GetDB())
+	auth.SetServiceAccounts(serviceAccountRepo)
+	serviceAccountHandler := handler.NewServiceAccountHandler(serviceAccountRepo)
+	apiKeyRepo := repository.NewPostgresAPIKeyRepository(database.GetDB())
+	auth.SetAPIKeys(apiKeyRepo)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyRepo)
+	secretHandler := handler.NewSecretHandler(newSecretKV(cfg), cfg.OpenBao.SecretsPrefix)
+	idempotent := idempotency.Middleware(repository.NewPostgresIdempotencyRepository(database.GetDB()))
 
 	// Serve static files
 	e.Static("/", "web")
@@ -76,17 +158,40 @@ func main() {
 			"message": "Casdoor Integration Demo API",
 			"demo":    "Visit http://localhost:8080 for interactive demo",
 			"endpoints": map[string]string{
-				"login":           "POST /api/auth/login - Direct login with username/password",
+				"login":           "POST /api/auth/login - Direct login with username/password (429 with Retry-After after repeated failures)",
 				"microsoft":       "GET /api/auth/microsoft/login - Get Microsoft SSO login URL",
 				"callback":        "GET /api/auth/callback?code=xxx&state=xxx - OAuth callback",
+				"token":           "POST /api/auth/token grant_type=client_credentials&client_id=&client_secret= - Service account token from Casdoor (Casbin subject svc:<name>)",
 				"me":              "GET /api/auth/me - Get current user info (requires Bearer token)",
+				"openbao-token":   "POST /api/auth/openbao-token - Short-lived OpenBao token with policies from your Casbin roles; DELETE or POST /api/auth/logout revokes it",
+				"api-keys":        "POST /api/auth/api-keys {\"name\", \"scopes\": [\"orders:list\"], \"expires_in\": \"720h\"} - Personal API key (shown once) for X-API-Key or Bearer; GET lists, DELETE /api/auth/api-keys/:id revokes",
 				"profile":         "GET /api/users/profile - Get user profile (requires Bearer token)",
 				"protected":       "GET /api/protected - Access protected resource (requires Bearer token)",
+				"secrets":         "GET /api/secrets - Your secret folders in OpenBao; GET|PUT|DELETE /api/secrets/users/<name>/<key> or groups/<group>/<key> (trailing / lists; Casbin checks each path)",
 				"users":           "GET /api/users - Get all users (admin only, requires Bearer token)",
-				"transactions":    "GET /api/transactions - Get all transactions (admin only)",
-				"my-transactions": "GET /api/transactions/my - Get my transactions",
-				"orders":          "GET /api/orders - Get all orders (admin only)",
-				"my-orders":       "GET /api/orders/my - Get my orders",
+				"transactions":    "GET /api/transactions - List the transactions you may read (Casbin data-scope policies become the query filter)",
+				"my-transactions": "GET /api/transactions/my - Get my transactions plus delegated and shared ones (each with an access flag)",
+				"products":        "GET /api/products - Product catalog; POST/PUT/DELETE /api/products[/:id] manage it (admin only)",
+				"create-order":    "POST /api/orders {\"sku\", \"quantity\", \"shipping_address\"} - Order a catalog product; price comes from the catalog and stock is reserved",
+				"orders":          "GET /api/orders - List the orders you may read (Casbin data-scope policies become the query filter)",
+				"my-orders":       "GET /api/orders/my - Get my orders plus delegated and shared ones (each with an access flag)",
+				"approve":         "POST /api/transactions/:id/approve - Approve a transaction above the approval threshold (approver role)",
+				"reject":          "POST /api/transactions/:id/reject - Reject a transaction awaiting approval (approver role)",
+				"balance":         "GET /api/accounts/:id/balance - Get account balance and ledger entries",
+				"order-status":    "PUT /api/orders/:id/status - Change order status (validated lifecycle transition)",
+				"order-history":   "GET /api/orders/:id/history - Get order status history",
+				"order-refund":    "POST /api/orders/:id/refunds - Refund a cancelled or returned order (full or partial, never more than paid)",
+				"reports":         "GET /api/reports/orders|transactions?period=day|week|month&group_by=status,type,user&from=&to=&format=csv - Counts and totals (own records unless admin)",
+				"export":          "GET /api/orders/export, GET /api/transactions/export?format=csv|xlsx - Stream all records matching the list filters (Casbin action export)",
+				"import":          "POST /api/orders/import, POST /api/transactions/import - CSV or XLSX upload, validated per row, applied all-or-nothing (Casbin action import)",
+				"sharing":         "POST /api/orders/:id/shares, POST /api/transactions/:id/shares {\"user\", \"role\": \"viewer|editor\", \"expires_at\"} - Share a record (owner only); GET lists and DELETE .../shares/:user revokes",
+				"delegations":     "POST /api/delegations {\"delegate\", \"expires_at\"} - Let another user act for you (on_behalf_of when creating orders and transactions); GET lists, DELETE /api/delegations/:delegate revokes",
+				"fields":          "Field-level Casbin permissions (\"orders.total\", \"read\") hide or mask fields in responses and exports; (\"products.price\", \"write\") guards product writes",
+				"encryption":      "Transaction descriptions and order shipping addresses are encrypted with OpenBao transit (OPENBAO_TRANSIT_KEY); GET /api/admin/encryption, POST /api/admin/encryption/rotate|rewrap (admin only)",
+				"service-account": "GET|POST /api/admin/service-accounts, GET|PUT|DELETE /api/admin/service-accounts/:name, POST|DELETE .../:name/policies {\"object\", \"action\"} or {\"role\"} (admin only)",
+				"idempotency":     "POST /api/orders and POST /api/transactions accept an Idempotency-Key header; retries replay the first response",
+				"list-params":     "limit, cursor, sort (created_at|amount, '-' for desc), status, type, user, currency, from, to, min_amount, max_amount",
+				"money":           "Amounts are decimal strings with an ISO-4217 currency: {\"amount\": \"12.34\", \"currency\": \"USD\"}",
 			},
 		})
 	})
@@ -101,6 +206,8 @@ func main() {
 	authGroup.GET("/microsoft/login", microsoftHandler.MicrosoftSSO)
 	authGroup.GET("/callback", authHandler.Callback)
 	authGroup.POST("/logout", authHandler.Logout)
+	// Case 4: Service accounts (OAuth client_credentials)
+	authGroup.POST("/token", serviceAccountHandler.IssueToken)
 
 	// Protected routes (with Casbin authorization)
 	protectedGroup := e.Group("/api")
@@ -109,23 +216,68 @@ func main() {
 	{
 		// Auth & User endpoints
 		protectedGroup.GET("/auth/me", authHandler.GetUserInfo)
+		casbin.SetRoutePermission(protectedGroup.POST("/auth/openbao-token", openBaoTokenHandler.IssueToken), "openbao-token", "create")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/auth/openbao-token", openBaoTokenHandler.RevokeTokens), "openbao-token", "revoke")
+		casbin.SetRoutePermission(protectedGroup.GET("/auth/api-keys", apiKeyHandler.GetAPIKeys), "api-keys", "read")
+		casbin.SetRoutePermission(protectedGroup.POST("/auth/api-keys", apiKeyHandler.CreateAPIKey), "api-keys", "create")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/auth/api-keys/:id", apiKeyHandler.RevokeAPIKey), "api-keys", "delete")
 		protectedGroup.GET("/users/profile", userHandler.GetProfile)
 		protectedGroup.GET("/protected", userHandler.ProtectedResource)
-		protectedGroup.GET("/secrets", userHandler.GetSecrets)
 		protectedGroup.GET("/users", userHandler.GetUsers)
 
 		// Transaction endpoints
-		protectedGroup.GET("/transactions", transactionHandler.GetTransactions)           // Admin only
+		casbin.SetRoutePermission(protectedGroup.GET("/transactions", transactionHandler.GetTransactions), "transactions", "list") // Rows limited by data-scope policies
 		protectedGroup.GET("/transactions/my", transactionHandler.GetMyTransactions)     // User's own
-		protectedGroup.GET("/transactions/:id", transactionHandler.GetTransaction)       // Ownership check
-		protectedGroup.POST("/transactions", transactionHandler.CreateTransaction)       // User can create
+		casbin.SetRoutePermission(protectedGroup.GET("/transactions/:id", transactionHandler.GetTransaction), "transactions", "read") // Owner, delegate or share grantee
+		casbin.SetRoutePermission(protectedGroup.GET("/transactions/:id/shares", sharingHandler.GetTransactionShares), "transactions", "share") // Owner only
+		casbin.SetRoutePermission(protectedGroup.POST("/transactions/:id/shares", sharingHandler.ShareTransaction), "transactions", "share")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/transactions/:id/shares/:user", sharingHandler.RevokeTransactionShare), "transactions", "share")
+		casbin.SetRoutePermission(protectedGroup.GET("/transactions/export", bulkHandler.ExportTransactions), "transactions", "export")
+		casbin.SetRoutePermission(protectedGroup.POST("/transactions/import", bulkHandler.ImportTransactions), "transactions", "import")
+		protectedGroup.POST("/transactions", transactionHandler.CreateTransaction, idempotent) // User can create (Idempotency-Key supported)
+		casbin.SetRoutePermission(protectedGroup.POST("/transactions/:id/approve", transactionHandler.ApproveTransaction), "transactions", "approve") // Approver role, not the creator
+		casbin.SetRoutePermission(protectedGroup.POST("/transactions/:id/reject", transactionHandler.RejectTransaction), "transactions", "reject")
+
+		// Account endpoints
+		casbin.SetRoutePermission(protectedGroup.GET("/accounts/:id/balance", accountHandler.GetBalance), "accounts", "read-balance") // Ownership check
+
+		// Product catalog: everyone reads, admins manage
+		casbin.SetRoutePermission(protectedGroup.GET("/products", productHandler.GetProducts), "products", "read")
+		casbin.SetRoutePermission(protectedGroup.GET("/products/:id", productHandler.GetProduct), "products", "read")
+		casbin.SetRoutePermission(protectedGroup.POST("/products", productHandler.CreateProduct), "products", "create")
+		casbin.SetRoutePermission(protectedGroup.PUT("/products/:id", productHandler.UpdateProduct), "products", "update")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/products/:id", productHandler.DeleteProduct), "products", "delete")
 
 		// Order endpoints
-		protectedGroup.GET("/orders", orderHandler.GetOrders)                           // Admin only
+		casbin.SetRoutePermission(protectedGroup.GET("/orders", orderHandler.GetOrders), "orders", "list") // Rows limited by data-scope policies
 		protectedGroup.GET("/orders/my", orderHandler.GetMyOrders)                     // User's own
-		protectedGroup.GET("/orders/:id", orderHandler.GetOrder)                       // Ownership check
-		protectedGroup.POST("/orders", orderHandler.CreateOrder)                       // User can create
-		protectedGroup.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus)       // Admin only
+		casbin.SetRoutePermission(protectedGroup.GET("/orders/:id", orderHandler.GetOrder), "orders", "read") // Owner, delegate or share grantee
+		casbin.SetRoutePermission(protectedGroup.GET("/orders/:id/shares", sharingHandler.GetOrderShares), "orders", "share") // Owner only
+		casbin.SetRoutePermission(protectedGroup.POST("/orders/:id/shares", sharingHandler.ShareOrder), "orders", "share")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/orders/:id/shares/:user", sharingHandler.RevokeOrderShare), "orders", "share")
+		casbin.SetRoutePermission(protectedGroup.GET("/orders/export", bulkHandler.ExportOrders), "orders", "export")
+		casbin.SetRoutePermission(protectedGroup.POST("/orders/import", bulkHandler.ImportOrders), "orders", "import")
+		protectedGroup.POST("/orders", orderHandler.CreateOrder, idempotent)           // User can create (Idempotency-Key supported)
+		// Checked as ("orders", "update-status") instead of the raw path; the handler
+		// then checks the transition (users may only cancel pending orders they can edit)
+		casbin.SetRoutePermission(protectedGroup.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus), "orders", "update-status")
+		casbin.SetRoutePermission(protectedGroup.GET("/orders/:id/history", orderHandler.GetOrderHistory), "orders", "read-history") // Ownership check
+		casbin.SetRoutePermission(protectedGroup.POST("/orders/:id/refunds", orderHandler.RefundOrder, idempotent), "orders", "refund") // Admin only
+
+		// Secrets in OpenBao; each secret path is authorized in the handler
+		casbin.SetRoutePermission(protectedGroup.GET("/secrets", secretHandler.GetSecretFolders), "secrets", "access")
+		casbin.SetRoutePermission(protectedGroup.GET("/secrets/*", secretHandler.GetSecret), "secrets", "access")
+		casbin.SetRoutePermission(protectedGroup.PUT("/secrets/*", secretHandler.PutSecret), "secrets", "access")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/secrets/*", secretHandler.DeleteSecret), "secrets", "access")
+
+		// Delegation: let another user act for you
+		casbin.SetRoutePermission(protectedGroup.GET("/delegations", sharingHandler.GetDelegations), "delegations", "read")
+		casbin.SetRoutePermission(protectedGroup.POST("/delegations", sharingHandler.CreateDelegation), "delegations", "create")
+		casbin.SetRoutePermission(protectedGroup.DELETE("/delegations/:delegate", sharingHandler.RevokeDelegation), "delegations", "delete")
+
+		// Report endpoints: scoped to the caller's own records unless ("reports", "read-all")
+		casbin.SetRoutePermission(protectedGroup.GET("/reports/orders", reportHandler.GetOrderReport), "reports", "read")
+		casbin.SetRoutePermission(protectedGroup.GET("/reports/transactions", reportHandler.GetTransactionReport), "reports", "read")
 	}
 
 	// Admin routes (policy management)
@@ -143,6 +295,16 @@ func main() {
 		adminGroup.GET("/debug/casbin-rules", debugHandler.GetCasbinRules)
 		adminGroup.POST("/debug/fix-casbin", fixHandler.FixCasbinRules)
 		adminGroup.POST("/reload-policies", adminHandler.ReloadPolicies)
+		adminGroup.GET("/encryption", encryptionHandler.GetEncryptionStatus)
+		adminGroup.POST("/encryption/rotate", encryptionHandler.RotateEncryptionKey)
+		adminGroup.POST("/encryption/rewrap", encryptionHandler.RewrapEncryptedFields)
+		adminGroup.GET("/service-accounts", serviceAccountHandler.GetServiceAccounts)
+		adminGroup.POST("/service-accounts", serviceAccountHandler.CreateServiceAccount)
+		adminGroup.GET("/service-accounts/:name", serviceAccountHandler.GetServiceAccount)
+		adminGroup.PUT("/service-accounts/:name", serviceAccountHandler.UpdateServiceAccount)
+		adminGroup.DELETE("/service-accounts/:name", serviceAccountHandler.DeleteServiceAccount)
+		adminGroup.POST("/service-accounts/:name/policies", serviceAccountHandler.AddServiceAccountPolicy)
+		adminGroup.DELETE("/service-accounts/:name/policies", serviceAccountHandler.RemoveServiceAccountPolicy)
 	}
 
 	// Start server
@@ -151,7 +313,104 @@ func main() {
 	log.Printf("Casdoor endpoint: %s", cfg.Casdoor.Endpoint)
 	log.Printf("Browser Access: http://localhost:8080")
 
+	if tlsServer != nil {
+		go tlsServer.Run(context.Background())
+		e.TLSServer.Addr = address
+		e.TLSServer.TLSConfig = tlsServer.TLSConfig()
+		log.Printf("mTLS enabled (client certificates %s)", cfg.MTLS.ClientAuth)
+		if err := e.StartServer(e.TLSServer); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server:", err)
+		}
+		return
+	}
 	if err := e.Start(address); err != nil && err != http.ErrServerClosed {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// newTLSServer issues the server certificate from OpenBao PKI when
+// MTLS_SERVER_ROLE is set; nil serves plain HTTP
+func newTLSServer(cfg *config.Config) (*mtls.Server, error) {
+	if cfg.MTLS.ServerRole == "" {
+		return nil, nil
+	}
+	client := config.OpenBao()
+	if client == nil {
+		return nil, fmt.Errorf("MTLS_SERVER_ROLE requires OPENBAO_ADDR")
+	}
+	return mtls.NewServer(context.Background(), client.PKI(cfg.OpenBao.PKIMount), cfg.MTLS)
+}
+
+// newSecretKV returns the OpenBao KV engine for /api/secrets, or nil when
+// OPENBAO_ADDR is unset
+func newSecretKV(cfg *config.Config) *openbao.KV {
+	client := config.OpenBao()
+	if client == nil {
+		log.Println("Warning: OPENBAO_ADDR not set, /api/secrets is disabled")
+		return nil
+	}
+	return client.KV(cfg.OpenBao.KVMount)
+}
+
+// newTokenIssuer returns the issuer of user OpenBao tokens, or nil when
+// OPENBAO_ADDR is unset
+func newTokenIssuer(cfg *config.Config) (*baotoken.Issuer, error) {
+	client := config.OpenBao()
+	if client == nil {
+		return nil, nil
+	}
+	return baotoken.New(client, baotoken.Options{
+		Method:       cfg.OpenBao.UserTokenAuth,
+		TokenRole:    cfg.OpenBao.UserTokenRole,
+		JWTMount:     cfg.OpenBao.JWTMount,
+		JWTRole:      cfg.OpenBao.JWTRole,
+		TTL:          cfg.OpenBao.UserTokenTTL,
+		PolicyPrefix: cfg.OpenBao.PolicyPrefix,
+	})
+}
+
+// requestLogConfig is the default request log without query strings,
+// which can carry credentials such as the OAuth code on /api/auth/callback
+func requestLogConfig() middleware.LoggerConfig {
+	logConfig := middleware.DefaultLoggerConfig
+	logConfig.Format = strings.Replace(logConfig.Format, `"uri":"${uri}"`, `"path":"${path}"`, 1)
+	return logConfig
+}
+
+// newLoginLimiter keeps direct login failure counters in the backend
+// selected by LOGIN_LIMIT_BACKEND
+func newLoginLimiter(cfg *config.Config) (*loginlimit.Limiter, error) {
+	var repo repository.LoginFailureRepository
+	switch cfg.Login.Backend {
+	case loginlimit.BackendPostgres, "":
+		repo = repository.NewPostgresLoginFailureRepository(database.GetDB())
+	case loginlimit.BackendMemory:
+		repo = repository.NewMemoryLoginFailureRepository()
+	default:
+		return nil, fmt.Errorf("unknown LOGIN_LIMIT_BACKEND %q (use postgres or memory)", cfg.Login.Backend)
+	}
+	return loginlimit.New(repo, cfg.Login), nil
+}
+
+// initFieldEncryption enables transit encryption of the fields tagged
+// serializer:transit when OPENBAO_TRANSIT_KEY is set
+func initFieldEncryption(cfg *config.Config) error {
+	key := cfg.OpenBao.TransitKey
+	if key == "" {
+		return nil
+	}
+	client := config.OpenBao()
+	if client == nil {
+		return fmt.Errorf("OPENBAO_TRANSIT_KEY requires OPENBAO_ADDR")
+	}
+
+	transit := client.Transit(cfg.OpenBao.TransitMount)
+	if err := transit.CreateKey(context.Background(), key); err != nil {
+		return fmt.Errorf("transit key %s: %w", key, err)
+	}
+	if err := fieldcrypt.Configure(transit, key, fieldcrypt.Mode(cfg.OpenBao.FieldDecrypt)); err != nil {
+		return err
+	}
+	log.Printf("Field encryption enabled (transit key %s, decrypt %s)", key, cfg.OpenBao.FieldDecrypt)
+	return nil
+}
@@ -2,17 +2,29 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Server   ServerConfig
 	Casdoor  CasdoorConfig
 	Database DatabaseConfig
+	Casbin   CasbinConfig
+	Approval ApprovalConfig
+	OpenBao  OpenBaoConfig
+	MTLS     MTLSConfig
+	APIKeys  APIKeyConfig
+	Login    LoginLimitConfig
 }
 
 type ServerConfig struct {
 	Port string
 	Host string
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For sent by
+	// proxies on private networks; otherwise the connection address is used
+	TrustProxyHeaders bool
 }
 
 type CasdoorConfig struct {
@@ -31,6 +43,112 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	DBName   string
+	// CredentialsRole, when set, takes short-lived credentials for this
+	// role from OpenBao's database secrets engine (at CredentialsMount)
+	// instead of User and Password
+	CredentialsRole  string
+	CredentialsMount string
+}
+
+type CasbinConfig struct {
+	// MethodActions maps HTTP methods to Casbin actions. Methods that are
+	// not listed are denied by the authorization middleware.
+	MethodActions map[string]string
+	// Adapter stores the policy: "postgres" (the application database),
+	// "sqlite" (SQLitePath), "file" (PolicyFile) or "openbao" (KV v2
+	// secret OpenBaoPath, written with check-and-set)
+	Adapter     string
+	SQLitePath  string
+	PolicyFile  string
+	OpenBaoPath string
+}
+
+type ApprovalConfig struct {
+	// Thresholds above which transactions need approval, as a list of
+	// "[role:]CURRENCY=amount" entries, e.g. "USD=10000,vip:USD=50000"
+	Thresholds string
+}
+
+type OpenBaoConfig struct {
+	// Address of the OpenBao server. Empty disables /api/secrets and bao://
+	// references.
+	Address   string
+	Namespace string
+	// AuthMethod is "token" (Token) or "approle" (RoleID and SecretID).
+	// Empty picks approle when RoleID is set.
+	AuthMethod   string
+	Token        string
+	AppRoleMount string
+	RoleID       string
+	SecretID     string
+	// RefreshInterval bounds how long resolved bao:// values go without a
+	// re-read; leases and token renewals usually refresh them sooner
+	RefreshInterval time.Duration
+	// TransitKey, when set, encrypts the fields tagged serializer:transit
+	// with this key of the transit engine at TransitMount
+	TransitKey   string
+	TransitMount string
+	// FieldDecrypt is "always" (decrypt on load) or "authorized" (decrypt
+	// in responses for callers with (<resource>.<field>, decrypt))
+	FieldDecrypt string
+	// UserTokenAuth is how user tokens are issued: "token" creates them
+	// with the token role UserTokenRole, "jwt" logs in to JWTMount/JWTRole
+	// with the caller's Casdoor JWT and scopes the result down
+	UserTokenAuth string
+	UserTokenRole string
+	UserTokenTTL  time.Duration
+	JWTMount      string
+	JWTRole       string
+	// PolicyPrefix followed by a Casbin role names the role's OpenBao policy
+	PolicyPrefix string
+	// PKIMount is where the PKI engine issuing mTLS certificates is mounted
+	PKIMount string
+	// KVMount is where the KV v2 engine is mounted
+	KVMount string
+	// SecretsPrefix is the folder under KVMount that holds the users/ and
+	// groups/ secret folders
+	SecretsPrefix string
+}
+
+type MTLSConfig struct {
+	// ServerRole is the OpenBao PKI role that issues the server
+	// certificate. Empty serves plain HTTP.
+	ServerRole string
+	// ServerName is the certificate's common name; AltNames are extra DNS
+	// names and IP addresses
+	ServerName string
+	AltNames   []string
+	CertTTL    time.Duration
+	// ClientAuth is "optional" (bearer tokens still work) or "require"
+	ClientAuth string
+	// ServiceDomain is the DNS suffix of service certificates: a client
+	// certificate for billing.<ServiceDomain> is the Casbin subject
+	// <SubjectPrefix>billing
+	ServiceDomain string
+	SubjectPrefix string
+}
+
+type APIKeyConfig struct {
+	// DefaultTTL is the lifetime of keys created without an expiry;
+	// MaxTTL caps the expiry a user may ask for
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+type LoginLimitConfig struct {
+	// Backend keeps failure counters: "postgres" (shared by all instances)
+	// or "memory"
+	Backend string
+	// Each failed login delays the next attempt for the same username and
+	// client IP by BackoffBase, doubling up to BackoffMax. MaxFailuresPerUser
+	// failures for a username, or MaxFailuresPerIP from an address, lock it
+	// out for Lockout. Counters are forgotten after Window without failures.
+	MaxFailuresPerUser int
+	MaxFailuresPerIP   int
+	BackoffBase        time.Duration
+	BackoffMax         time.Duration
+	Lockout            time.Duration
+	Window             time.Duration
 }
 
 func LoadConfig() *Config {
@@ -38,6 +156,8 @@ func LoadConfig() *Config {
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 			Host: getEnv("SERVER_HOST", "localhost"),
+
+			TrustProxyHeaders: getEnv("TRUST_PROXY_HEADERS", "false") == "true",
 		},
 		Casdoor: CasdoorConfig{
 			Endpoint:     getEnv("CASDOOR_ENDPOINT", "http://localhost:8000"),
@@ -52,9 +172,88 @@ func LoadConfig() *Config {
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
 			User:     getEnv("DB_USER", "casbin"),
-			Password: getEnv("DB_PASSWORD", "casbinpw"),
+			Password: getEnv("DB_PASSWORD", ""),
 			DBName:   getEnv("DB_NAME", "casdoor"),
+
+			CredentialsRole:  getEnv("DB_OPENBAO_ROLE", ""),
+			CredentialsMount: getEnv("DB_OPENBAO_MOUNT", "database"),
+		},
+		Casbin: CasbinConfig{
+			MethodActions: getEnvMap("CASBIN_METHOD_ACTIONS", "GET=read,POST=write,PUT=update,PATCH=update,DELETE=delete"),
+			Adapter:       getEnv("CASBIN_ADAPTER", "postgres"),
+			SQLitePath:    getEnv("CASBIN_SQLITE_PATH", "casbin.db"),
+			PolicyFile:    getEnv("CASBIN_POLICY_FILE", "config/policy.csv"),
+			OpenBaoPath:   getEnv("CASBIN_OPENBAO_PATH", "casbin/policy"),
+		},
+		Approval: ApprovalConfig{
+			Thresholds: getEnv("APPROVAL_THRESHOLDS", "USD=10000.00,EUR=10000.00,GBP=10000.00"),
+		},
+		OpenBao: OpenBaoConfig{
+			Address:         getEnv("OPENBAO_ADDR", ""),
+			Namespace:       getEnv("OPENBAO_NAMESPACE", ""),
+			AuthMethod:      getEnv("OPENBAO_AUTH_METHOD", ""),
+			Token:           getEnv("OPENBAO_TOKEN", ""),
+			AppRoleMount:    getEnv("OPENBAO_APPROLE_MOUNT", "approle"),
+			RoleID:          getEnv("OPENBAO_ROLE_ID", ""),
+			SecretID:        getEnv("OPENBAO_SECRET_ID", ""),
+			RefreshInterval: getEnvDuration("OPENBAO_REFRESH_INTERVAL", 5*time.Minute),
+			TransitKey:      getEnv("OPENBAO_TRANSIT_KEY", ""),
+			TransitMount:    getEnv("OPENBAO_TRANSIT_MOUNT", "transit"),
+			FieldDecrypt:    getEnv("FIELD_DECRYPT", "always"),
+			UserTokenAuth:   getEnv("OPENBAO_USER_TOKEN_AUTH", "token"),
+			UserTokenRole:   getEnv("OPENBAO_USER_TOKEN_ROLE", "casdoor-user"),
+			UserTokenTTL:    getEnvDuration("OPENBAO_USER_TOKEN_TTL", 15*time.Minute),
+			JWTMount:        getEnv("OPENBAO_JWT_MOUNT", "jwt"),
+			JWTRole:         getEnv("OPENBAO_JWT_ROLE", "casdoor"),
+			PolicyPrefix:    getEnv("OPENBAO_POLICY_PREFIX", "casbin-"),
+			PKIMount:        getEnv("OPENBAO_PKI_MOUNT", "pki"),
+			KVMount:         getEnv("OPENBAO_KV_MOUNT", "secret"),
+			SecretsPrefix:   getEnv("OPENBAO_SECRETS_PREFIX", "app"),
+		},
+		MTLS: MTLSConfig{
+			ServerRole:    getEnv("MTLS_SERVER_ROLE", ""),
+			ServerName:    getEnv("MTLS_SERVER_NAME", getEnv("SERVER_HOST", "localhost")),
+			AltNames:      getEnvList("MTLS_SERVER_ALT_NAMES", "localhost,127.0.0.1"),
+			CertTTL:       getEnvDuration("MTLS_CERT_TTL", 24*time.Hour),
+			ClientAuth:    getEnv("MTLS_CLIENT_AUTH", "optional"),
+			ServiceDomain: getEnv("MTLS_SERVICE_DOMAIN", "svc.internal"),
+			SubjectPrefix: getEnv("MTLS_SUBJECT_PREFIX", "svc:"),
+		},
+		APIKeys: APIKeyConfig{
+			DefaultTTL: getEnvDuration("API_KEY_DEFAULT_TTL", 30*24*time.Hour),
+			MaxTTL:     getEnvDuration("API_KEY_MAX_TTL", 365*24*time.Hour),
 		},
+		Login: LoginLimitConfig{
+			Backend:            getEnv("LOGIN_LIMIT_BACKEND", "postgres"),
+			MaxFailuresPerUser: getEnvInt("LOGIN_MAX_FAILURES_PER_USER", 10),
+			MaxFailuresPerIP:   getEnvInt("LOGIN_MAX_FAILURES_PER_IP", 50),
+			BackoffBase:        getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+			BackoffMax:         getEnvDuration("LOGIN_BACKOFF_MAX", time.Minute),
+			Lockout:            getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+			Window:             getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		},
+	}
+}
+
+// SecretField is a configuration value that may hold a bao:// reference
+type SecretField struct {
+	// Env is the variable the value came from
+	Env string
+	// Required values must be non-empty once references are resolved
+	Required bool
+	Value    *string
+}
+
+// SecretFields returns the values that may be given as bao:// references
+// instead of plain text
+func (c *Config) SecretFields() []SecretField {
+	static := c.Database.CredentialsRole == ""
+	return []SecretField{
+		{Env: "DB_USER", Required: static, Value: &c.Database.User},
+		{Env: "DB_PASSWORD", Required: static, Value: &c.Database.Password},
+		{Env: "CASDOOR_CLIENT_ID", Value: &c.Casdoor.ClientID},
+		{Env: "CASDOOR_CLIENT_SECRET", Required: true, Value: &c.Casdoor.ClientSecret},
+		{Env: "CASDOOR_CERTIFICATE", Value: &c.Casdoor.Certificate},
 	}
 }
 
@@ -65,3 +264,44 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvDuration parses a duration such as "5m"; invalid values fall back
+// to the default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getEnv(key, "")); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
+
+// getEnvInt parses a positive integer; invalid values fall back to the
+// default
+func getEnvInt(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(getEnv(key, "")); err == nil && n > 0 {
+		return n
+	}
+	return defaultValue
+}
+
+// getEnvList parses a comma-separated list, skipping empty items
+func getEnvList(key, defaultValue string) []string {
+	var result []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvMap parses a "KEY=value,KEY=value" list. Keys are upper-cased.
+func getEnvMap(key, defaultValue string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, defaultValue), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" || v == "" {
+			continue
+		}
+		result[strings.ToUpper(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	return result
+}
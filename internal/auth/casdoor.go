@@ -29,6 +29,15 @@ type CasdoorClaims struct {
 	ID          string   `json:"id"`
 	Roles       []string `json:"roles"`
 	IsAdmin     bool     `json:"isAdmin"`
+
+	// Type is "application" on client_credentials tokens, whose
+	// authorized party (azp) is the application's client ID
+	Type            string `json:"type"`
+	AuthorizedParty string `json:"azp"`
+
+	// APIKeyID and Scopes are set when the request used an API key
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
 	jwt.RegisteredClaims
 }
 
@@ -127,9 +136,7 @@ func GetPublicKey() (*rsa.PublicKey, error) {
 // VerifyToken verifies a JWT token from Casdoor
 func VerifyToken(tokenString string) (*CasdoorClaims, error) {
 	publicKey, err := GetPublicKey()
-	fmt.Println("\n publicKey: ", publicKey)
 	if err != nil {
-		fmt.Println("publicKey-err: ", err)
 		return nil, fmt.Errorf("failed to get public key: %w", err)
 	}
 
@@ -141,7 +148,6 @@ func VerifyToken(tokenString string) (*CasdoorClaims, error) {
 	})
 
 	if err != nil {
-		fmt.Println("token-err: ", err)
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/config"
+)
+
+// userStatusTTL is how long a Casdoor user lookup is reused, which bounds
+// how long a demoted or disabled user keeps access through API keys
+const userStatusTTL = 30 * time.Second
+
+// ErrUserDisabled is returned for users that are forbidden, deleted or
+// missing in Casdoor
+var ErrUserDisabled = errors.New("user is disabled or no longer exists")
+
+// UserStatus is the part of a Casdoor user that decides access
+type UserStatus struct {
+	IsAdmin     bool `json:"isAdmin"`
+	IsForbidden bool `json:"isForbidden"`
+	IsDeleted   bool `json:"isDeleted"`
+}
+
+type cachedUserStatus struct {
+	status    *UserStatus
+	fetchedAt time.Time
+}
+
+var (
+	userStatusMu    sync.Mutex
+	userStatusCache = map[string]cachedUserStatus{}
+)
+
+// CurrentUserStatus returns the user's current Casdoor status, looked up
+// at most once per userStatusTTL. Disabled users return ErrUserDisabled.
+func CurrentUserStatus(ctx context.Context, owner, name string) (*UserStatus, error) {
+	id := owner + "/" + name
+	now := time.Now()
+
+	userStatusMu.Lock()
+	cached, ok := userStatusCache[id]
+	userStatusMu.Unlock()
+	if !ok || now.Sub(cached.fetchedAt) >= userStatusTTL {
+		status, err := fetchUserStatus(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		cached = cachedUserStatus{status: status, fetchedAt: now}
+		userStatusMu.Lock()
+		userStatusCache[id] = cached
+		userStatusMu.Unlock()
+	}
+
+	if cached.status == nil || cached.status.IsForbidden || cached.status.IsDeleted {
+		return nil, ErrUserDisabled
+	}
+	return cached.status, nil
+}
+
+// fetchUserStatus reads a user from Casdoor's get-user API with the
+// application's client credentials. A missing user returns nil.
+func fetchUserStatus(ctx context.Context, id string) (*UserStatus, error) {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+
+	query := url.Values{}
+	query.Set("id", id)
+	query.Set("clientId", cfg.Casdoor.ClientID)
+	query.Set("clientSecret", cfg.Casdoor.ClientSecret)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.Casdoor.Endpoint+"/api/get-user?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string      `json:"status"`
+		Msg    string      `json:"msg"`
+		Data   *UserStatus `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode user %s (status %d): %w", id, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Status != "ok" {
+		return nil, fmt.Errorf("failed to look up user %s: %s", id, body.Msg)
+	}
+	return body.Data, nil
+}
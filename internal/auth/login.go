@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,10 @@ import (
 	"casdoor-casbin-openbao/internal/config"
 )
 
+// ErrInvalidCredentials is returned when Casdoor rejects the username or
+// password, as opposed to Casdoor being unreachable
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
 // LoginRequest represents login request
 type LoginRequest struct {
 	Username string `json:"username"`
@@ -57,8 +62,6 @@ func DirectLogin(username, password string) (string, error) {
 		return "", fmt.Errorf("failed to marshal login request: %w", err)
 	}
 
-	fmt.Println("loginReq: ", string(jsonData))
-
 	resp, err := http.Post(loginURL, "application/json", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return "", fmt.Errorf("failed to request login: %w", err)
@@ -76,7 +79,7 @@ func DirectLogin(username, password string) (string, error) {
 	}
 
 	if loginResp.Status != "ok" {
-		return "", fmt.Errorf("login failed: %s", loginResp.Msg)
+		return "", fmt.Errorf("%w: %s", ErrInvalidCredentials, loginResp.Msg)
 	}
 
 	if loginResp.Data == "" {
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -8,14 +9,33 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/mtls"
 )
 
 // AuthMiddleware validates JWT tokens from Casdoor
 func AuthMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// Personal API keys come in X-API-Key or as a Bearer token
+			if key, ok := apiKeyFromRequest(c); ok {
+				claims, err := APIKeyClaims(c.Request().Context(), key)
+				if errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrAPIKeyExpired) || errors.Is(err, ErrUserDisabled) {
+					return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+				}
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify API key")
+				}
+				setUser(c, claims)
+				return next(c)
+			}
+
 			authHeader := c.Request().Header.Get("Authorization")
 			if authHeader == "" {
+				// Internal services authenticate with a client certificate
+				if subject, ok := mtls.Subject(c.Request().TLS); ok {
+					setUser(c, &CasdoorClaims{Owner: "service", Name: subject, DisplayName: subject, ID: subject})
+					return next(c)
+				}
 				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
 			}
 
@@ -30,26 +50,48 @@ func AuthMiddleware() echo.MiddlewareFunc {
 			if err != nil {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token: "+err.Error())
 			}
-			fmt.Println("[ANNNNN]", claims)
 
-			// Store user info in context
-			c.Set("user", claims)
-			c.Set("user_id", claims.GetUserID())
-			c.Set("user_name", claims.Name)
-			c.Set("user_email", claims.Email)
-			c.Set("is_admin", claims.IsAdmin)
+			// Machine tokens act as their service account
+			if claims.IsApplication() {
+				claims, _, err = ServiceAccountClaims(c.Request().Context(), claims)
+				if errors.Is(err, ErrUnknownServiceAccount) || errors.Is(err, ErrServiceAccountDisabled) {
+					return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+				}
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify service account")
+				}
+			}
 
+			setUser(c, claims)
 			return next(c)
 		}
 	}
 }
 
+// setUser stores user info in context
+func setUser(c echo.Context, claims *CasdoorClaims) {
+	c.Set("user", claims)
+	c.Set("user_id", claims.GetUserID())
+	c.Set("user_name", claims.Name)
+	c.Set("user_email", claims.Email)
+	c.Set("is_admin", claims.IsAdmin)
+}
+
 // GetUserFromContext retrieves user claims from echo context
 func GetUserFromContext(c echo.Context) (*CasdoorClaims, bool) {
 	user, ok := c.Get("user").(*CasdoorClaims)
 	return user, ok
 }
 
+// BearerToken returns the token of an "Authorization: Bearer <token>" header
+func BearerToken(c echo.Context) (string, bool) {
+	parts := strings.Split(c.Request().Header.Get("Authorization"), " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
 // RequireAdmin middleware that requires admin role
 func RequireAdmin() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -63,6 +105,15 @@ func RequireAdmin() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusForbidden, "admin access required")
 			}
 
+			// API keys reach admin routes only with an "admin:<action>" scope
+			action := ""
+			if cfg := config.GetConfig(); cfg != nil {
+				action = cfg.Casbin.MethodActions[c.Request().Method]
+			}
+			if !user.InScope("admin", action) {
+				return echo.NewHTTPError(http.StatusForbidden, "access denied: outside the API key's scopes")
+			}
+
 			return next(c)
 		}
 	}
@@ -101,8 +152,5 @@ func GetLoginURL(state string) string {
 		cfg.Casdoor.Endpoint,
 		params.Encode(),
 	)
-
-	fmt.Println("AAAA", loginURL)
-
 	return loginURL
 }
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+// TokenTypeApplication is the "type" claim Casdoor puts on tokens issued
+// by the client_credentials grant
+const TokenTypeApplication = "application"
+
+var (
+	// ErrUnknownServiceAccount is returned for machine tokens whose client
+	// ID is not registered as a service account
+	ErrUnknownServiceAccount = errors.New("client is not a registered service account")
+	// ErrServiceAccountDisabled is returned for machine tokens of a
+	// disabled service account
+	ErrServiceAccountDisabled = errors.New("service account is disabled")
+)
+
+var serviceAccounts repository.ServiceAccountRepository
+
+// SetServiceAccounts sets the registry machine tokens are checked
+// against. Without it every machine token is rejected.
+func SetServiceAccounts(repo repository.ServiceAccountRepository) {
+	serviceAccounts = repo
+}
+
+// IsApplication reports whether the token was issued to an application
+// (client_credentials) rather than to a user
+func (c *CasdoorClaims) IsApplication() bool {
+	return c.Type == TokenTypeApplication
+}
+
+// ClientID returns the application the token was issued to
+func (c *CasdoorClaims) ClientID() string {
+	if c.AuthorizedParty != "" {
+		return c.AuthorizedParty
+	}
+	if len(c.Audience) > 0 {
+		return c.Audience[0]
+	}
+	return ""
+}
+
+// ServiceAccountClaims maps a verified machine token to the claims of its
+// service account, named by its Casbin subject ("svc:<name>"). Service
+// accounts are never admins; they get access only through Casbin.
+func ServiceAccountClaims(ctx context.Context, claims *CasdoorClaims) (*CasdoorClaims, *model.ServiceAccount, error) {
+	if serviceAccounts == nil {
+		return nil, nil, ErrUnknownServiceAccount
+	}
+	clientID := claims.ClientID()
+	if clientID == "" {
+		return nil, nil, ErrUnknownServiceAccount
+	}
+
+	account, err := serviceAccounts.GetByClientID(ctx, clientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrUnknownServiceAccount
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up service account: %w", err)
+	}
+	if account.Disabled {
+		return nil, nil, ErrServiceAccountDisabled
+	}
+
+	subject := account.Subject()
+	registered := claims.RegisteredClaims
+	registered.Subject = subject
+	return &CasdoorClaims{
+		Owner:            "service",
+		Name:             subject,
+		DisplayName:      account.Name,
+		ID:               subject,
+		Type:             TokenTypeApplication,
+		AuthorizedParty:  clientID,
+		RegisteredClaims: registered,
+	}, account, nil
+}
+
+// ClientCredentialsToken is the token response of the client_credentials grant
+type ClientCredentialsToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// ErrInvalidClient is returned when Casdoor rejects the client credentials
+var ErrInvalidClient = errors.New("invalid client credentials")
+
+// ClientCredentialsLogin exchanges an application's client ID and secret
+// for an access token with the OAuth client_credentials grant. The
+// application must enable that grant in Casdoor.
+func ClientCredentialsLogin(ctx context.Context, clientID, clientSecret string) (*ClientCredentialsToken, error) {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Casdoor.Endpoint+"/api/login/oauth/access_token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Casdoor reports OAuth errors with status 200 and an "error" field
+	var body struct {
+		ClientCredentialsToken
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	switch {
+	case body.Error == "invalid_client" || body.Error == "unauthorized_client" ||
+		resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrInvalidClient
+	case body.Error != "":
+		return nil, fmt.Errorf("token request failed: %s %s", body.Error, body.ErrorDescription)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("token request failed: status %d", resp.StatusCode)
+	case body.AccessToken == "":
+		return nil, fmt.Errorf("token request failed: no access token returned")
+	}
+	return &body.ClientCredentialsToken, nil
+}
@@ -0,0 +1,204 @@
+// Package baotoken exchanges verified Casdoor sessions for short-lived
+// OpenBao tokens whose policies follow the user's Casbin roles.
+//
+// A role (or g2 group) r maps to the OpenBao policy <prefix><r>; policies
+// that do not exist in OpenBao simply grant nothing. Issued tokens are
+// remembered per Casdoor session so logging out revokes them.
+package baotoken
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/openbao"
+)
+
+const (
+	// MethodToken creates tokens with a token role using the server's token
+	MethodToken = "token"
+	// MethodJWT logs in to the JWT auth method with the caller's Casdoor
+	// JWT and returns a child token limited to the derived policies
+	MethodJWT = "jwt"
+)
+
+// ErrNoPolicies is returned for users without any Casbin role
+var ErrNoPolicies = errors.New("no Casbin role maps to an OpenBao policy")
+
+// Options configures an Issuer
+type Options struct {
+	Method       string
+	TokenRole    string // auth/token/create/<TokenRole> (MethodToken)
+	JWTMount     string // auth/<JWTMount>/login (MethodJWT)
+	JWTRole      string
+	TTL          time.Duration
+	PolicyPrefix string
+}
+
+// Token is an issued user token
+type Token struct {
+	Token     string    `json:"token"`
+	Accessor  string    `json:"accessor"`
+	Policies  []string  `json:"policies"`
+	TTL       int       `json:"ttl"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Issuer issues and revokes user tokens
+type Issuer struct {
+	client *openbao.Client
+	opts   Options
+
+	mu       sync.Mutex
+	sessions map[string][]issued // Casdoor session → tokens to revoke at logout
+}
+
+type issued struct {
+	accessor string
+	expires  time.Time
+}
+
+// New returns an Issuer that uses client, the server's own OpenBao client,
+// for token roles and revocation
+func New(client *openbao.Client, opts Options) (*Issuer, error) {
+	if opts.Method != MethodToken && opts.Method != MethodJWT {
+		return nil, fmt.Errorf("unknown user token method %q (use token or jwt)", opts.Method)
+	}
+	if opts.TTL <= 0 {
+		return nil, fmt.Errorf("user token TTL must be positive")
+	}
+	return &Issuer{client: client, opts: opts, sessions: map[string][]issued{}}, nil
+}
+
+// Session identifies the Casdoor login a JWT belongs to: its jti, or a hash
+// of the token when it has none
+func Session(claims *auth.CasdoorClaims, jwt string) string {
+	if claims.RegisteredClaims.ID != "" {
+		return claims.RegisteredClaims.ID
+	}
+	sum := sha256.Sum256([]byte(jwt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Policies returns the OpenBao policies of user's Casbin roles and groups
+func (i *Issuer) Policies(user string) ([]string, error) {
+	roles, err := casbin.GetRolesForUser(user)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var policies []string
+	for _, role := range roles {
+		policy := i.opts.PolicyPrefix + role
+		if !seen[policy] {
+			seen[policy] = true
+			policies = append(policies, policy)
+		}
+	}
+	if len(policies) == 0 {
+		return nil, ErrNoPolicies
+	}
+	sort.Strings(policies)
+	return policies, nil
+}
+
+// Issue returns a new token for user, valid for the configured TTL at most
+func (i *Issuer) Issue(ctx context.Context, user, session, jwt string) (*Token, error) {
+	policies, err := i.Policies(user)
+	if err != nil {
+		return nil, err
+	}
+	req := openbao.TokenRequest{
+		Policies:    policies,
+		TTL:         i.opts.TTL,
+		DisplayName: "casdoor-" + user,
+		Meta:        map[string]string{"user": user, "source": "casdoor"},
+	}
+
+	var token *openbao.IssuedToken
+	revoke := "" // accessor whose revocation ends the token
+	switch i.opts.Method {
+	case MethodJWT:
+		login, err := i.client.LoginJWT(ctx, i.opts.JWTMount, i.opts.JWTRole, jwt)
+		if err != nil {
+			return nil, fmt.Errorf("JWT login failed: %w", err)
+		}
+		// Revoking the login token also revokes the child
+		revoke = login.Accessor
+		token, err = i.client.WithToken(login.Token).CreateToken(ctx, req)
+		if err != nil {
+			i.client.RevokeAccessor(ctx, login.Accessor)
+			return nil, fmt.Errorf("failed to scope token: %w", err)
+		}
+	default:
+		req.Role = i.opts.TokenRole
+		if token, err = i.client.CreateToken(ctx, req); err != nil {
+			return nil, fmt.Errorf("failed to create token: %w", err)
+		}
+		revoke = token.Accessor
+	}
+
+	expires := time.Now().Add(token.Lease.Duration)
+	i.remember(session, issued{accessor: revoke, expires: expires})
+	log.Printf("OpenBao token %s issued to %s (policies %v, ttl %s)", token.Accessor, user, token.Policies, token.Lease.Duration)
+
+	return &Token{
+		Token:     token.Token,
+		Accessor:  token.Accessor,
+		Policies:  token.Policies,
+		TTL:       int(token.Lease.Duration / time.Second),
+		ExpiresAt: expires.UTC(),
+	}, nil
+}
+
+// Revoke revokes every unexpired token issued for session and returns how
+// many were revoked
+func (i *Issuer) Revoke(ctx context.Context, session string) (int, error) {
+	i.mu.Lock()
+	tokens := i.sessions[session]
+	delete(i.sessions, session)
+	i.mu.Unlock()
+
+	revoked := 0
+	var errs []error
+	for _, t := range tokens {
+		if time.Now().After(t.expires) {
+			continue
+		}
+		if err := i.client.RevokeAccessor(ctx, t.accessor); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		revoked++
+	}
+	return revoked, errors.Join(errs...)
+}
+
+// remember records a token of session and forgets expired ones
+func (i *Issuer) remember(session string, t issued) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	now := time.Now()
+	for s, tokens := range i.sessions {
+		live := tokens[:0]
+		for _, token := range tokens {
+			if now.Before(token.expires) {
+				live = append(live, token)
+			}
+		}
+		if len(live) == 0 {
+			delete(i.sessions, s)
+		} else {
+			i.sessions[s] = live
+		}
+	}
+	i.sessions[session] = append(i.sessions[session], t)
+}
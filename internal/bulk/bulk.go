@@ -0,0 +1,113 @@
+// Package bulk converts orders and transactions to and from spreadsheet
+// rows and imports them all-or-nothing.
+//
+// Exported files can be imported again: read-only columns such as total,
+// status, sku, created_by or account_id are ignored on import, and ids are
+// kept when given.
+package bulk
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// OrderColumns is the header of order exports
+var OrderColumns = []string{
+	"id", "user_id", "sku", "product_name", "quantity", "price", "total", "currency",
+	"status", "created_at", "created_by", "payment_transaction_id",
+}
+
+// OrderRow formats an order in OrderColumns order
+func OrderRow(o model.Order) []string {
+	return []string{
+		o.ID, o.UserID, o.SKU, o.ProductName, strconv.Itoa(o.Quantity), o.Price.String(), o.Total.String(), o.Total.Currency,
+		o.Status, formatTime(&o.CreatedAt), o.CreatedBy, o.PaymentID,
+	}
+}
+
+// TransactionColumns is the header of transaction exports
+var TransactionColumns = []string{
+	"id", "user_id", "account_id", "type", "status", "amount", "currency", "description",
+	"counterparty", "order_id", "created_at", "created_by", "reviewed_by", "reviewed_at", "review_note",
+}
+
+// TransactionRow formats a transaction in TransactionColumns order
+func TransactionRow(t model.Transaction) []string {
+	return []string{
+		t.ID, t.UserID, t.AccountID, t.Type, t.Status, t.Amount.String(), t.Amount.Currency, t.Description,
+		t.Counterparty, t.OrderID, formatTime(&t.CreatedAt), t.CreatedBy, t.ReviewedBy, formatTime(t.ReviewedAt), t.ReviewNote,
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sheet gives access to the cells of data rows by column name
+type sheet struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// newSheet reads the header row. Column names are matched case-insensitively.
+func newSheet(rows [][]string, required []string) (*sheet, []RowError) {
+	if len(rows) == 0 {
+		return nil, []RowError{{Row: 1, Message: "missing header row"}}
+	}
+
+	s := &sheet{columns: map[string]int{}, rows: rows[1:]}
+	for i, name := range rows[0] {
+		s.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var errs []RowError
+	for _, name := range required {
+		if _, ok := s.columns[name]; !ok {
+			errs = append(errs, RowError{Row: 1, Column: name, Message: "missing required column"})
+		}
+	}
+	return s, errs
+}
+
+// cell returns the trimmed value of column in data row i
+func (s *sheet) cell(i int, column string) string {
+	col, ok := s.columns[column]
+	if !ok || col >= len(s.rows[i]) {
+		return ""
+	}
+	return strings.TrimSpace(s.rows[i][col])
+}
+
+// empty reports whether data row i has no values
+func (s *sheet) empty(i int) bool {
+	for _, cell := range s.rows[i] {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rowNumber is the spreadsheet row number of data row i; the header is row 1
+func rowNumber(i int) int {
+	return i + 2
+}
+
+// parseTime reads an optional RFC3339 or YYYY-MM-DD timestamp
+func parseTime(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
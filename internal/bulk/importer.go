@@ -0,0 +1,284 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+// MaxImportRows is the largest number of data rows accepted in one import
+const MaxImportRows = 10000
+
+// ErrInvalidRows is returned when at least one row fails; nothing is imported
+var ErrInvalidRows = errors.New("import has invalid rows")
+
+// RowError describes why a row cannot be imported. Row is the spreadsheet
+// row number, with the header in row 1.
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"error"`
+}
+
+// Result summarizes an import
+type Result struct {
+	Imported        int        `json:"imported"`
+	PendingApproval int        `json:"pending_approval,omitempty"`
+	Errors          []RowError `json:"errors,omitempty"`
+}
+
+// Importer validates rows and applies them in one database transaction
+type Importer struct {
+	transactor   repository.Transactor
+	orders       repository.OrderRepository
+	transactions repository.TransactionRepository
+	ledger       *ledger.Service
+}
+
+// NewImporter creates an importer
+func NewImporter(transactor repository.Transactor, orders repository.OrderRepository, transactions repository.TransactionRepository, ledger *ledger.Service) *Importer {
+	return &Importer{
+		transactor:   transactor,
+		orders:       orders,
+		transactions: transactions,
+		ledger:       ledger,
+	}
+}
+
+// ImportOrders imports historical orders. Every row is validated first;
+// if any row is invalid nothing is written and all row errors are
+// returned with ErrInvalidRows. Imported orders are records only: they do
+// not reserve stock or charge a payment, so they are always imported as
+// pending orders without a SKU, created by the importer. Status changes go
+// through PUT /api/orders/:id/status like any other order's.
+func (i *Importer) ImportOrders(ctx context.Context, rows [][]string, importedBy *auth.CasdoorClaims) (*Result, error) {
+	s, errs := newSheet(rows, []string{"user_id", "product_name", "quantity", "price"})
+	if errs != nil {
+		return &Result{Errors: errs}, ErrInvalidRows
+	}
+	if len(s.rows) > MaxImportRows {
+		return &Result{Errors: []RowError{{Row: rowNumber(MaxImportRows), Message: fmt.Sprintf("at most %d rows can be imported at once", MaxImportRows)}}}, ErrInvalidRows
+	}
+
+	var orders []model.Order
+	var rowNumbers []int
+	seen := map[string]int{}
+	now := time.Now()
+
+	for r := range s.rows {
+		if s.empty(r) {
+			continue
+		}
+		row := rowNumber(r)
+		fail := func(column, message string) {
+			errs = append(errs, RowError{Row: row, Column: column, Message: message})
+		}
+
+		order := model.Order{
+			ID:          s.cell(r, "id"),
+			UserID:      s.cell(r, "user_id"),
+			ProductName: s.cell(r, "product_name"),
+			Status:      model.OrderStatusPending,
+			CreatedBy:   importedBy.Name,
+		}
+		if order.UserID == "" {
+			fail("user_id", "is required")
+		}
+		if order.ProductName == "" {
+			fail("product_name", "is required")
+		}
+
+		quantity, err := strconv.Atoi(s.cell(r, "quantity"))
+		if err != nil || quantity <= 0 {
+			fail("quantity", "must be a positive integer")
+		}
+		order.Quantity = quantity
+
+		currency := s.cell(r, "currency")
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+		price, err := money.Parse(s.cell(r, "price"), currency)
+		switch {
+		case err != nil:
+			fail("price", err.Error())
+		case price.IsNegative():
+			fail("price", "must not be negative")
+		default:
+			order.Price = price
+			if order.Total, err = price.Mul(int64(quantity)); err != nil {
+				fail("price", "total is out of range")
+			}
+		}
+
+		createdAt, ok := parseTime(s.cell(r, "created_at"))
+		if !ok {
+			fail("created_at", "must be RFC3339 or YYYY-MM-DD")
+		}
+		order.CreatedAt = createdAt
+		if order.CreatedAt.IsZero() {
+			order.CreatedAt = now
+		}
+
+		if order.ID == "" {
+			order.ID = model.NewID("ord")
+		} else if first, dup := seen[order.ID]; dup {
+			fail("id", fmt.Sprintf("duplicates row %d", first))
+		} else if _, err := i.orders.Get(ctx, order.ID); err == nil {
+			fail("id", "order already exists")
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		seen[order.ID] = row
+
+		orders = append(orders, order)
+		rowNumbers = append(rowNumbers, row)
+	}
+	if errs != nil {
+		return &Result{Errors: errs}, ErrInvalidRows
+	}
+
+	err := i.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for n := range orders {
+			if err := i.orders.Create(ctx, &orders[n]); err != nil {
+				return fmt.Errorf("row %d: %w", rowNumbers[n], err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Imported: len(orders)}, nil
+}
+
+// ImportTransactions records deposits, withdrawals and transfers through
+// the ledger, so balances, overdraft protection and approval thresholds
+// apply exactly as for POST /api/transactions. Every row is validated
+// first; rows that fail while posting (e.g. insufficient funds) roll back
+// the whole import. Payments and refunds are created by orders and cannot
+// be imported; deposits need ("transactions", "deposit") as in the API.
+func (i *Importer) ImportTransactions(ctx context.Context, rows [][]string, importedBy *auth.CasdoorClaims) (*Result, error) {
+	s, errs := newSheet(rows, []string{"user_id", "type", "amount"})
+	if errs != nil {
+		return &Result{Errors: errs}, ErrInvalidRows
+	}
+	if len(s.rows) > MaxImportRows {
+		return &Result{Errors: []RowError{{Row: rowNumber(MaxImportRows), Message: fmt.Sprintf("at most %d rows can be imported at once", MaxImportRows)}}}, ErrInvalidRows
+	}
+
+	var txns []model.Transaction
+	var rowNumbers []int
+	seen := map[string]int{}
+	now := time.Now()
+
+	canDeposit, err := casbin.EnforceUser(importedBy, "transactions", "deposit")
+	if err != nil {
+		return nil, fmt.Errorf("deposit permission check failed: %w", err)
+	}
+
+	for r := range s.rows {
+		if s.empty(r) {
+			continue
+		}
+		row := rowNumber(r)
+		fail := func(column, message string) {
+			errs = append(errs, RowError{Row: row, Column: column, Message: message})
+		}
+
+		txn := model.Transaction{
+			ID:           s.cell(r, "id"),
+			UserID:       s.cell(r, "user_id"),
+			Type:         s.cell(r, "type"),
+			Status:       model.TransactionStatusPending,
+			Description:  s.cell(r, "description"),
+			Counterparty: s.cell(r, "counterparty"),
+			CreatedBy:    importedBy.Name,
+		}
+		if txn.UserID == "" {
+			fail("user_id", "is required")
+		}
+		if model.IsOrderTransactionType(txn.Type) {
+			fail("type", txn.Type+" transactions are created through orders")
+		}
+		if txn.Type == model.TransactionTypeDeposit && !canDeposit {
+			fail("type", "not allowed to create deposits")
+		}
+
+		currency := s.cell(r, "currency")
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+		amount, err := money.Parse(s.cell(r, "amount"), currency)
+		if err != nil {
+			fail("amount", err.Error())
+		}
+		txn.Amount = amount
+
+		createdAt, ok := parseTime(s.cell(r, "created_at"))
+		if !ok {
+			fail("created_at", "must be RFC3339 or YYYY-MM-DD")
+		}
+		txn.CreatedAt = createdAt
+		if txn.CreatedAt.IsZero() {
+			txn.CreatedAt = now
+		}
+
+		if err == nil && !model.IsOrderTransactionType(txn.Type) {
+			if err := ledger.Validate(&txn); err != nil {
+				fail("", err.Error())
+			}
+		}
+
+		if txn.ID == "" {
+			txn.ID = model.NewID("txn")
+		} else if first, dup := seen[txn.ID]; dup {
+			fail("id", fmt.Sprintf("duplicates row %d", first))
+		} else if _, err := i.transactions.Get(ctx, txn.ID); err == nil {
+			fail("id", "transaction already exists")
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		seen[txn.ID] = row
+
+		txns = append(txns, txn)
+		rowNumbers = append(rowNumbers, row)
+	}
+	if errs != nil {
+		return &Result{Errors: errs}, ErrInvalidRows
+	}
+
+	result := &Result{}
+	err = i.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for n := range txns {
+			if err := i.ledger.Record(ctx, &txns[n]); err != nil {
+				if errors.Is(err, ledger.ErrInvalidTransaction) || errors.Is(err, repository.ErrInsufficientFunds) {
+					result.Errors = []RowError{{Row: rowNumbers[n], Message: err.Error()}}
+					return ErrInvalidRows
+				}
+				return fmt.Errorf("row %d: %w", rowNumbers[n], err)
+			}
+			if txns[n].Status == model.TransactionStatusPendingApproval {
+				result.PendingApproval++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidRows) {
+			return result, err
+		}
+		return nil, err
+	}
+	result.Imported = len(txns)
+	return result, nil
+}
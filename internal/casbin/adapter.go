@@ -0,0 +1,59 @@
+package casbin
+
+import (
+	"fmt"
+
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/database"
+	"github.com/casbin/casbin/v2/persist"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Policy storage backends (CASBIN_ADAPTER)
+const (
+	AdapterPostgres = "postgres"
+	AdapterSQLite   = "sqlite"
+	AdapterFile     = "file"
+	AdapterOpenBao  = "openbao"
+)
+
+// adapterName is the configured adapter, for logs
+func adapterName(cfg config.CasbinConfig) string {
+	if cfg.Adapter == "" {
+		return AdapterPostgres
+	}
+	return cfg.Adapter
+}
+
+// newAdapter returns the policy storage selected by CASBIN_ADAPTER
+func newAdapter(cfg config.CasbinConfig) (persist.Adapter, error) {
+	switch cfg.Adapter {
+	case AdapterPostgres, "":
+		db := database.GetDB()
+		if db == nil {
+			return nil, fmt.Errorf("database not initialized")
+		}
+		return gormadapter.NewAdapterByDBWithCustomTable(db, &gormadapter.CasbinRule{}, "casbin_rule")
+
+	case AdapterSQLite:
+		db, err := gorm.Open(sqlite.Open(cfg.SQLitePath), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", cfg.SQLitePath, err)
+		}
+		return gormadapter.NewAdapterByDBWithCustomTable(db, &gormadapter.CasbinRule{}, "casbin_rule")
+
+	case AdapterFile:
+		return newDocumentAdapter(&fileStore{path: cfg.PolicyFile}), nil
+
+	case AdapterOpenBao:
+		client := config.OpenBao()
+		if client == nil {
+			return nil, fmt.Errorf("CASBIN_ADAPTER=openbao requires OPENBAO_ADDR")
+		}
+		kv := client.KV(config.GetConfig().OpenBao.KVMount)
+		return newDocumentAdapter(&kvStore{kv: kv, path: cfg.OpenBaoPath}), nil
+	}
+	return nil, fmt.Errorf("unknown CASBIN_ADAPTER %q (use postgres, sqlite, file or openbao)", cfg.Adapter)
+}
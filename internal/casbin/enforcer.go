@@ -4,20 +4,15 @@ import (
 	"fmt"
 	"log"
 
-	"casdoor-casbin-openbao/internal/database"
+	"casdoor-casbin-openbao/internal/config"
 	"github.com/casbin/casbin/v2"
-	gormadapter "github.com/casbin/gorm-adapter/v3"
 )
 
 var Enforcer *casbin.Enforcer
 
 func InitEnforcer() error {
-	db := database.GetDB()
-	if db == nil {
-		return fmt.Errorf("database not initialized")
-	}
-
-	adapter, err := gormadapter.NewAdapterByDBWithCustomTable(db, &gormadapter.CasbinRule{}, "casbin_rule")
+	cfg := config.GetConfig().Casbin
+	adapter, err := newAdapter(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create casbin adapter: %w", err)
 	}
@@ -32,15 +27,24 @@ func InitEnforcer() error {
 		return fmt.Errorf("failed to load policy: %w", err)
 	}
 
-	// Auto-initialize default policies if DB is empty
+	// Auto-initialize default policies if the store is empty; they are
+	// saved in one write rather than one per rule
 	if len(Enforcer.GetPolicy()) == 0 {
 		log.Println("No policies found, initializing default policies...")
+		Enforcer.EnableAutoSave(false)
 		if err := initDefaultPoliciesInternal(); err != nil {
 			log.Printf("Warning: failed to initialize default policies: %v", err)
+		} else if err := Enforcer.SavePolicy(); err != nil {
+			log.Printf("Warning: failed to save default policies: %v", err)
 		}
+		Enforcer.EnableAutoSave(true)
+	}
+
+	if err := PruneExpiredGrants(); err != nil {
+		log.Printf("Warning: %v", err)
 	}
 
-	log.Println("Casbin enforcer initialized successfully")
+	log.Printf("Casbin enforcer initialized successfully (%s adapter)", adapterName(cfg))
 	return nil
 }
 
@@ -60,22 +64,136 @@ func initDefaultPoliciesInternal() error {
 		{"admin", "/api/users", "read"},
 		{"admin", "/api/admin/*", "write"},
 		{"admin", "/api/protected", "read"},
-		{"admin", "/api/secrets", "read"},
 		{"admin", "/api/auth/me", "read"},
 		{"user", "/api/users/profile", "read"},
 		{"user", "/api/protected", "read"},
 		{"user", "/api/auth/me", "read"},
+
+		// Secrets (route metadata); each path is then checked as ("secrets:<path>", read|list|write|delete)
+		{"admin", "secrets", "access"},
+		{"user", "secrets", "access"},
+		{"admin", "openbao-token", "create"},
+		{"admin", "openbao-token", "revoke"},
+		{"user", "openbao-token", "create"},
+		{"user", "openbao-token", "revoke"},
+		{"admin", "api-keys", "read"},
+		{"admin", "api-keys", "create"},
+		{"admin", "api-keys", "delete"},
+		{"user", "api-keys", "read"},
+		{"user", "api-keys", "create"},
+		{"user", "api-keys", "delete"},
+		{"admin", "secrets:*", "read"},
+		{"admin", "secrets:*", "list"},
+		{"admin", "secrets:*", "write"},
+		{"admin", "secrets:*", "delete"},
+		{"user", "secrets:users/{user}/*", "read"},       // Own folder
+		{"user", "secrets:users/{user}/*", "list"},
+		{"user", "secrets:users/{user}/*", "write"},
+		{"user", "secrets:users/{user}/*", "delete"},
+		{"user", "secrets:groups/{group}/*", "read"},     // Folders of the user's groups (g2), read-only
+		{"user", "secrets:groups/{group}/*", "list"},
 		
 		// Transaction endpoints
-		{"admin", "/api/transactions", "read"},           // Admin can see all transactions
-		{"admin", "/api/transactions/*", "read"},         // Admin can see specific transactions
+		{"admin", "transactions", "list"},                // Rows filtered by the data-scope rules below
+		{"user", "transactions", "list"},
+		{"approver", "transactions", "list"},
+		{"admin", "transactions", "scope:all"},
+		{"user", "transactions", "scope:own"},
+		{"user", "transactions", "scope:shared"},
+		{"approver", "transactions", "scope:status=pending_approval"}, // Approval queue
 		{"user", "/api/transactions/my", "read"},         // User can see own transactions
 		{"user", "/api/transactions", "write"},          // User can create transactions
+		{"admin", "transactions", "deposit"},             // Deposits credit from outside the ledger
+		{"admin", "transactions", "approve"},             // Maker-checker: approve/reject above threshold
+		{"admin", "transactions", "reject"},
+		{"admin", "transactions", "export"},
+		{"admin", "transactions", "import"},
+		{"approver", "transactions", "approve"},
+		{"approver", "transactions", "reject"},
 		
+		// Account endpoints
+		{"admin", "accounts", "read-balance"},
+		{"user", "accounts", "read-balance"},             // Ownership checked in handler
+
+		// Product catalog (route metadata)
+		{"admin", "products", "read"},
+		{"admin", "products", "create"},
+		{"admin", "products", "update"},
+		{"admin", "products", "delete"},
+		{"user", "products", "read"},
+
+		// Reports (route metadata); read-all lifts the own-records scope
+		{"admin", "reports", "read"},
+		{"admin", "reports", "read-all"},
+		{"user", "reports", "read"},
+
+		// Field-level permissions: ("<resource>.<field>" or "<resource>.*", read|write)
+		{"admin", "orders.*", "read"},
+		{"user", "orders.*", "read"},                    // Warehouse sees orders without price/total
+		{"admin", "transactions.*", "read"},
+		{"user", "transactions.*", "read"},
+		{"approver", "transactions.*", "read"},
+		{"admin", "products.*", "write"},                 // price, stock and active
+		{"admin", "orders.*", "write"},                   // quantity, shipping_address, status and refund_amount
+		{"user", "orders.quantity", "write"},
+		{"user", "orders.shipping_address", "write"},
+		{"user", "orders.status", "write"},               // Cancelling; update-status:<status> still applies
+		{"warehouse", "orders.status", "write"},
+		{"admin", "transactions.*", "write"},             // amount, type, description, counterparty and status
+		{"user", "transactions.amount", "write"},
+		{"user", "transactions.type", "write"},
+		{"user", "transactions.description", "write"},
+		{"user", "transactions.counterparty", "write"},
+		{"approver", "transactions.status", "write"},     // Approve and reject
+		{"admin", "orders.shipping_address", "decrypt"},  // Encrypted fields, checked when FIELD_DECRYPT=authorized
+		{"user", "orders.shipping_address", "decrypt"},
+		{"warehouse", "orders.shipping_address", "decrypt"},
+		{"admin", "transactions.description", "decrypt"},
+		{"user", "transactions.description", "decrypt"},
+
+		// Single records (route metadata); owner, delegate, share or data scope checked in handler
+		{"admin", "orders", "read"},
+		{"user", "orders", "read"},
+		{"warehouse", "orders", "read"},
+		{"admin", "transactions", "read"},
+		{"user", "transactions", "read"},
+		{"approver", "transactions", "read"},
+
+		// Sharing and delegation (route metadata); only owners may share
+		{"admin", "orders", "share"},
+		{"user", "orders", "share"},
+		{"admin", "transactions", "share"},
+		{"user", "transactions", "share"},
+		{"admin", "delegations", "read"},
+		{"admin", "delegations", "create"},
+		{"admin", "delegations", "delete"},
+		{"user", "delegations", "read"},
+		{"user", "delegations", "create"},
+		{"user", "delegations", "delete"},
+
 		// Order endpoints
-		{"admin", "/api/orders", "read"},                 // Admin can see all orders
-		{"admin", "/api/orders/*", "read"},               // Admin can see specific orders
-		{"admin", "/api/orders/*", "update"},             // Admin can update order status
+		{"admin", "orders", "list"},                      // Rows filtered by the data-scope rules below
+		{"user", "orders", "list"},
+		{"warehouse", "orders", "list"},
+		{"admin", "orders", "scope:all"},
+		{"user", "orders", "scope:own"},
+		{"user", "orders", "scope:shared"},
+		{"warehouse", "orders", "scope:status=pending|processing|shipped"}, // Orders to fulfil
+		{"admin", "orders", "update-status"},             // Admin can update order status (route metadata)
+		{"admin", "orders", "update-status:processing"},  // Per-transition permissions
+		{"admin", "orders", "update-status:shipped"},
+		{"admin", "orders", "update-status:delivered"},
+		{"admin", "orders", "update-status:cancelled"},
+		{"admin", "orders", "update-status:returned"},
+		{"admin", "orders", "read-history"},
+		{"user", "orders", "read-history"},               // Ownership checked in handler
+		{"user", "orders", "update-status"},              // Owners and editors may cancel pending orders
+		{"admin", "orders", "refund"},                    // Further partial refunds of cancelled/returned orders
+		{"admin", "orders", "export"},
+		{"admin", "orders", "import"},
+		{"warehouse", "orders", "update-status"},         // Warehouse fulfils orders
+		{"warehouse", "orders", "update-status:processing"},
+		{"warehouse", "orders", "update-status:shipped"},
 		{"user", "/api/orders/my", "read"},               // User can see own orders
 		{"user", "/api/orders", "write"},                // User can create orders
 	}
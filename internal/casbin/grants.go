@@ -0,0 +1,244 @@
+package casbin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Grants are stored as "p2" policies: (grantee, object, role, expires, granted_by).
+// They are not part of the matcher; handlers resolve them with ResourceAccess.
+const grantPolicy = "p2"
+
+// Access roles, from the weakest to the strongest
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// How access to a resource was obtained
+const (
+	ViaOwner     = "owner"
+	ViaShared    = "shared"
+	ViaDelegated = "delegated"
+	ViaAdmin     = "admin"
+	ViaPolicy    = "policy"
+)
+
+// actFor is the role of a delegation grant on "user:<principal>"
+const actFor = "act-for"
+
+// ErrGrantNotFound is returned when revoking a grant that does not exist
+var ErrGrantNotFound = errors.New("grant not found")
+
+// Grant is a sharing grant on a single resource or a delegation
+type Grant struct {
+	Grantee   string     `json:"grantee"`
+	Object    string     `json:"object"`
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	GrantedBy string     `json:"granted_by"`
+}
+
+// ResourceObject names a single resource, e.g. "orders:ord_123"
+func ResourceObject(resource, id string) string {
+	return resource + ":" + id
+}
+
+// DelegationObject names the account a delegate may act for
+func DelegationObject(principal string) string {
+	return "user:" + principal
+}
+
+// IsValidShareRole reports whether role can be granted on a resource
+func IsValidShareRole(role string) bool {
+	return role == RoleViewer || role == RoleEditor
+}
+
+// Share grants grantee a role on one resource, replacing any earlier grant
+// for the same resource. A nil expiresAt never expires.
+func Share(resource, id, grantee, role, grantedBy string, expiresAt *time.Time) (*Grant, error) {
+	if !IsValidShareRole(role) {
+		return nil, fmt.Errorf("unknown share role %q", role)
+	}
+	return addGrant(Grant{Grantee: grantee, Object: ResourceObject(resource, id), Role: role, ExpiresAt: expiresAt, GrantedBy: grantedBy})
+}
+
+// Delegate lets delegate act for principal, with editor access to all of
+// the principal's resources, replacing any earlier delegation between them
+func Delegate(principal, delegate, grantedBy string, expiresAt *time.Time) (*Grant, error) {
+	return addGrant(Grant{Grantee: delegate, Object: DelegationObject(principal), Role: actFor, ExpiresAt: expiresAt, GrantedBy: grantedBy})
+}
+
+func addGrant(g Grant) (*Grant, error) {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return nil, fmt.Errorf("enforcer not initialized")
+	}
+
+	if _, err := enforcer.RemoveFilteredNamedPolicy(grantPolicy, 0, g.Grantee, g.Object); err != nil {
+		return nil, fmt.Errorf("failed to replace grant: %w", err)
+	}
+	if _, err := enforcer.AddNamedPolicy(grantPolicy, g.rule()); err != nil {
+		return nil, fmt.Errorf("failed to add grant: %w", err)
+	}
+	return &g, nil
+}
+
+// Revoke removes the grant of grantee on object
+func Revoke(grantee, object string) error {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return fmt.Errorf("enforcer not initialized")
+	}
+
+	removed, err := enforcer.RemoveFilteredNamedPolicy(grantPolicy, 0, grantee, object)
+	if err != nil {
+		return fmt.Errorf("failed to revoke grant: %w", err)
+	}
+	if !removed {
+		return ErrGrantNotFound
+	}
+	return nil
+}
+
+// GrantsOn returns the active grants on object
+func GrantsOn(object string) []Grant {
+	return activeGrants(1, object)
+}
+
+// GrantsFor returns the active grants held by grantee
+func GrantsFor(grantee string) []Grant {
+	return activeGrants(0, grantee)
+}
+
+// DelegationsFrom returns the active delegations given by principal
+func DelegationsFrom(principal string) []Grant {
+	return GrantsOn(DelegationObject(principal))
+}
+
+// DelegationsTo returns the active delegations held by delegate
+func DelegationsTo(delegate string) []Grant {
+	var result []Grant
+	for _, g := range GrantsFor(delegate) {
+		if g.Role == actFor {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// Principal returns the user a delegation grant lets its holder act for
+func (g Grant) Principal() string {
+	return strings.TrimPrefix(g.Object, "user:")
+}
+
+// CanActFor reports whether user holds an active delegation from principal
+func CanActFor(user, principal string) bool {
+	for _, g := range activeGrants(0, user, DelegationObject(principal)) {
+		if g.Role == actFor {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceAccess resolves the role user has on a resource owned by owner.
+// Ownership wins over delegation, which wins over a sharing grant. An
+// empty role means no access.
+func ResourceAccess(user, resource, id, owner string) (role, via string) {
+	if user == owner {
+		return RoleOwner, ViaOwner
+	}
+	if CanActFor(user, owner) {
+		return RoleEditor, ViaDelegated
+	}
+	for _, g := range activeGrants(0, user, ResourceObject(resource, id)) {
+		return g.Role, ViaShared
+	}
+	return "", ""
+}
+
+// SharedIDs returns the ids of resources shared with grantee and the role
+// granted on each
+func SharedIDs(grantee, resource string) map[string]string {
+	prefix := resource + ":"
+	ids := map[string]string{}
+	for _, g := range GrantsFor(grantee) {
+		if strings.HasPrefix(g.Object, prefix) {
+			ids[strings.TrimPrefix(g.Object, prefix)] = g.Role
+		}
+	}
+	return ids
+}
+
+// PruneExpiredGrants removes grants whose expiry has passed
+func PruneExpiredGrants() error {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return fmt.Errorf("enforcer not initialized")
+	}
+
+	now := time.Now()
+	var expired [][]string
+	for _, rule := range enforcer.GetNamedPolicy(grantPolicy) {
+		if g, ok := parseGrant(rule); !ok || g.expired(now) {
+			expired = append(expired, rule)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if _, err := enforcer.RemoveNamedPolicies(grantPolicy, expired); err != nil {
+		return fmt.Errorf("failed to prune grants: %w", err)
+	}
+	log.Printf("Pruned %d expired grants", len(expired))
+	return nil
+}
+
+// activeGrants returns unexpired grants matching a filtered p2 lookup
+func activeGrants(fieldIndex int, fieldValues ...string) []Grant {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var result []Grant
+	for _, rule := range enforcer.GetFilteredNamedPolicy(grantPolicy, fieldIndex, fieldValues...) {
+		if g, ok := parseGrant(rule); ok && !g.expired(now) {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+func (g Grant) rule() []string {
+	expires := ""
+	if g.ExpiresAt != nil {
+		expires = g.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return []string{g.Grantee, g.Object, g.Role, expires, g.GrantedBy}
+}
+
+func (g Grant) expired(now time.Time) bool {
+	return g.ExpiresAt != nil && !now.Before(*g.ExpiresAt)
+}
+
+func parseGrant(rule []string) (Grant, bool) {
+	if len(rule) < 5 {
+		return Grant{}, false
+	}
+	g := Grant{Grantee: rule[0], Object: rule[1], Role: rule[2], GrantedBy: rule[4]}
+	if rule[3] != "" {
+		t, err := time.Parse(time.RFC3339, rule[3])
+		if err != nil {
+			return Grant{}, false
+		}
+		g.ExpiresAt = &t
+	}
+	return g, true
+}
@@ -9,8 +9,12 @@ func InitDefaultPolicies() error {
 		return fmt.Errorf("enforcer not initialized")
 	}
 
+	// Sharing and delegation grants are user data, not defaults; keep them
+	grants := enforcer.GetNamedPolicy(grantPolicy)
+
 	// Clear existing policies
 	enforcer.ClearPolicy()
+	enforcer.GetModel().AddPolicies("p", grantPolicy, grants)
 
 	// Use internal function to add policies
 	return initDefaultPoliciesInternal()
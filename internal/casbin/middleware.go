@@ -2,11 +2,48 @@ package casbin
 
 import (
 	"net/http"
+	"sync"
 
 	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/config"
 	"github.com/labstack/echo/v4"
 )
 
+// RoutePermission is the Casbin object and action a route is checked against
+type RoutePermission struct {
+	Object string
+	Action string
+}
+
+var (
+	routePermissions     = map[string]RoutePermission{}
+	routePermissionsLock sync.RWMutex
+)
+
+// SetRoutePermission declares the Casbin object and action for a route,
+// overriding the default of raw request path and method-derived action.
+// Usage: casbin.SetRoutePermission(g.PUT("/orders/:id/status", h), "orders", "update-status")
+func SetRoutePermission(route *echo.Route, object, action string) *echo.Route {
+	routePermissionsLock.Lock()
+	defer routePermissionsLock.Unlock()
+
+	routePermissions[routeKey(route.Method, route.Path)] = RoutePermission{Object: object, Action: action}
+	return route
+}
+
+// GetRoutePermission returns the declared permission for a route, if any
+func GetRoutePermission(method, path string) (RoutePermission, bool) {
+	routePermissionsLock.RLock()
+	defer routePermissionsLock.RUnlock()
+
+	perm, ok := routePermissions[routeKey(method, path)]
+	return perm, ok
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
 // AuthzMiddleware checks authorization using Casbin
 func AuthzMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -16,15 +53,14 @@ func AuthzMiddleware() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "user not found in context")
 			}
 
-			// Get request info
-			path := c.Request().URL.Path
-			method := c.Request().Method
+			// Resolve object and action: route metadata wins over the raw path
+			object, action, ok := resolvePermission(c)
+			if !ok {
+				return echo.NewHTTPError(http.StatusForbidden, "access denied: method not mapped to an action")
+			}
 
-			// Convert HTTP method to action
-			action := getActionFromMethod(method)
-			
 			// Check permission: enforce(subject, object, action)
-			allowed, err := GetEnforcer().Enforce(user.Name, path, action)
+			allowed, err := GetEnforcer().Enforce(user.Name, object, action)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
 			}
@@ -33,22 +69,40 @@ func AuthzMiddleware() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusForbidden, "access denied")
 			}
 
+			// API keys only get the part of their owner's permissions in scope
+			if !user.InScope(object, action) {
+				return echo.NewHTTPError(http.StatusForbidden, "access denied: outside the API key's scopes")
+			}
+
 			return next(c)
 		}
 	}
 }
 
-func getActionFromMethod(method string) string {
-	switch method {
-	case "GET":
-		return "read"
-	case "POST":
-		return "write"
-	case "PUT", "PATCH":
-		return "update"
-	case "DELETE":
-		return "delete"
-	default:
-		return "read"
+// resolvePermission returns the object and action to enforce for the request
+func resolvePermission(c echo.Context) (string, string, bool) {
+	method := c.Request().Method
+
+	if perm, ok := GetRoutePermission(method, c.Path()); ok {
+		return perm.Object, perm.Action, true
+	}
+
+	action, ok := getActionFromMethod(method)
+	if !ok {
+		return "", "", false
+	}
+
+	return c.Request().URL.Path, action, true
+}
+
+// getActionFromMethod maps an HTTP method to a Casbin action using the
+// configured mapping. Unmapped methods are denied.
+func getActionFromMethod(method string) (string, bool) {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return "", false
 	}
-}
\ No newline at end of file
+
+	action, ok := cfg.Casbin.MethodActions[method]
+	return action, ok
+}
@@ -1,6 +1,10 @@
 package casbin
 
-import "fmt"
+import (
+	"fmt"
+
+	"casdoor-casbin-openbao/internal/auth"
+)
 
 // AddPolicy adds a policy rule
 func AddPolicy(sub, obj, act string) error {
@@ -104,4 +108,100 @@ func ReloadPolicies() error {
 	}
 
 	return enforcer.LoadPolicy()
-}
\ No newline at end of file
+}
+
+// Enforce checks whether sub may perform act on obj
+func Enforce(sub, obj, act string) (bool, error) {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return false, fmt.Errorf("enforcer not initialized")
+	}
+
+	return enforcer.Enforce(sub, obj, act)
+}
+
+// EnforceUser checks whether user may perform act on obj: their own
+// permissions, narrowed to the API key's scopes when they authenticated
+// with one
+func EnforceUser(user *auth.CasdoorClaims, obj, act string) (bool, error) {
+	ok, err := Enforce(user.Name, obj, act)
+	if err != nil || !ok {
+		return false, err
+	}
+	return user.InScope(obj, act), nil
+}
+
+// EnforceUserAny is EnforceUser for a resource covered by several policy
+// objects, e.g. a field and its resource's wildcard: the user needs one of
+// them, and an API key needs one of them in its scopes
+func EnforceUserAny(user *auth.CasdoorClaims, objs []string, act string) (bool, error) {
+	allowed := false
+	for _, obj := range objs {
+		ok, err := Enforce(user.Name, obj, act)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false, nil
+	}
+	for _, obj := range objs {
+		if user.InScope(obj, act) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetRolesForUser returns the user's roles (g, including inherited roles)
+// and groups (g2)
+func GetRolesForUser(user string) ([]string, error) {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return nil, fmt.Errorf("enforcer not initialized")
+	}
+
+	roles, err := enforcer.GetImplicitRolesForUser(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	for _, rule := range enforcer.GetFilteredNamedGroupingPolicy("g2", 0, user) {
+		roles = append(roles, rule[1])
+	}
+
+	return roles, nil
+}
+
+// GetPoliciesForSubject returns the policies granted directly to sub
+func GetPoliciesForSubject(sub string) [][]string {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return nil
+	}
+	return enforcer.GetFilteredPolicy(0, sub)
+}
+
+// RemoveSubject removes every policy, role (g) and group (g2) of sub
+func RemoveSubject(sub string) error {
+	enforcer := GetEnforcer()
+	if enforcer == nil {
+		return fmt.Errorf("enforcer not initialized")
+	}
+
+	if _, err := enforcer.RemoveFilteredPolicy(0, sub); err != nil {
+		return fmt.Errorf("failed to remove policies: %w", err)
+	}
+	if _, err := enforcer.RemoveFilteredGroupingPolicy(0, sub); err != nil {
+		return fmt.Errorf("failed to remove roles: %w", err)
+	}
+	if _, err := enforcer.RemoveFilteredNamedGroupingPolicy("g2", 0, sub); err != nil {
+		return fmt.Errorf("failed to remove groups: %w", err)
+	}
+
+	return nil
+}
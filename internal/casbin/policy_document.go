@@ -0,0 +1,353 @@
+package casbin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/openbao"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+const (
+	// storeTimeout bounds one read or write of the policy document
+	storeTimeout = 10 * time.Second
+	// documentRetries is how often an incremental change is retried when
+	// another writer got there first
+	documentRetries = 5
+)
+
+// ErrPolicyConflict is returned by SavePolicy when the stored policy
+// changed since it was loaded; reload the policy and apply the change again
+var ErrPolicyConflict = errors.New("policy was changed by another writer since it was loaded")
+
+// errStoreConflict is returned by policyStore.write on a version mismatch
+var errStoreConflict = errors.New("policy document version mismatch")
+
+// policyStore holds the policy as one CSV document in the Casbin file
+// format. Versions are opaque; write fails with errStoreConflict unless
+// the stored version still is version ("" when there is no document).
+type policyStore interface {
+	read(ctx context.Context) (doc string, version string, err error)
+	write(ctx context.Context, doc, version string) (newVersion string, err error)
+}
+
+// documentAdapter keeps the whole policy in a policyStore. Incremental
+// changes (auto-save) re-read the document and retry on conflicts, so
+// concurrent writers never overwrite each other's rules; SavePolicy
+// replaces the document only if nobody wrote since LoadPolicy.
+type documentAdapter struct {
+	store policyStore
+
+	mu      sync.Mutex
+	version string // version of the last load or save
+}
+
+func newDocumentAdapter(store policyStore) *documentAdapter {
+	return &documentAdapter{store: store}
+}
+
+// LoadPolicy implements persist.Adapter
+func (a *documentAdapter) LoadPolicy(m model.Model) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	doc, version, err := a.store.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read policy: %w", err)
+	}
+	rules, err := parsePolicyDocument(doc)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := persist.LoadPolicyArray(rule, m); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	a.version = version
+	a.mu.Unlock()
+	return nil
+}
+
+// SavePolicy implements persist.Adapter
+func (a *documentAdapter) SavePolicy(m model.Model) error {
+	var rules [][]string
+	for _, sec := range []string{"p", "g"} {
+		var ptypes []string
+		for ptype := range m[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+		for _, ptype := range ptypes {
+			for _, rule := range m[sec][ptype].Policy {
+				rules = append(rules, append([]string{ptype}, rule...))
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	version, err := a.store.write(ctx, formatPolicyDocument(rules), a.version)
+	if errors.Is(err, errStoreConflict) {
+		return ErrPolicyConflict
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save policy: %w", err)
+	}
+	a.version = version
+	return nil
+}
+
+// AddPolicy implements persist.Adapter
+func (a *documentAdapter) AddPolicy(sec, ptype string, rule []string) error {
+	return a.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+// AddPolicies implements persist.BatchAdapter
+func (a *documentAdapter) AddPolicies(sec, ptype string, rules [][]string) error {
+	return a.update(func(stored [][]string) [][]string {
+		for _, rule := range rules {
+			line := append([]string{ptype}, rule...)
+			if indexOfRule(stored, line) < 0 {
+				stored = append(stored, line)
+			}
+		}
+		return stored
+	})
+}
+
+// RemovePolicy implements persist.Adapter
+func (a *documentAdapter) RemovePolicy(sec, ptype string, rule []string) error {
+	return a.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+// RemovePolicies implements persist.BatchAdapter
+func (a *documentAdapter) RemovePolicies(sec, ptype string, rules [][]string) error {
+	return a.update(func(stored [][]string) [][]string {
+		for _, rule := range rules {
+			if i := indexOfRule(stored, append([]string{ptype}, rule...)); i >= 0 {
+				stored = append(stored[:i], stored[i+1:]...)
+			}
+		}
+		return stored
+	})
+}
+
+// RemoveFilteredPolicy implements persist.Adapter
+func (a *documentAdapter) RemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return a.update(func(stored [][]string) [][]string {
+		kept := stored[:0]
+		for _, line := range stored {
+			if !(line[0] == ptype && matchesFilter(line[1:], fieldIndex, fieldValues)) {
+				kept = append(kept, line)
+			}
+		}
+		return kept
+	})
+}
+
+// update applies change to the latest stored document, retrying when
+// another writer saves in between
+func (a *documentAdapter) update(change func([][]string) [][]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for attempt := 0; attempt < documentRetries; attempt++ {
+		doc, version, err := a.store.read(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read policy: %w", err)
+		}
+		rules, err := parsePolicyDocument(doc)
+		if err != nil {
+			return err
+		}
+		updated := formatPolicyDocument(change(rules))
+		if updated == doc {
+			return nil
+		}
+
+		newVersion, err := a.store.write(ctx, updated, version)
+		if errors.Is(err, errStoreConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save policy: %w", err)
+		}
+		// Only our own change happened since the load; a later SavePolicy
+		// loses nothing
+		if version == a.version {
+			a.version = newVersion
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to save policy: %w", ErrPolicyConflict)
+}
+
+func indexOfRule(rules [][]string, rule []string) int {
+	for i, r := range rules {
+		if strings.Join(r, "\x00") == strings.Join(rule, "\x00") {
+			return i
+		}
+	}
+	return -1
+}
+
+func matchesFilter(rule []string, fieldIndex int, fieldValues []string) bool {
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if fieldIndex+i >= len(rule) || rule[fieldIndex+i] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePolicyDocument reads the Casbin policy CSV format: one rule per
+// line, ptype first, "#" comments
+func parsePolicyDocument(doc string) ([][]string, error) {
+	var rules [][]string
+	for n, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := csv.NewReader(strings.NewReader(line))
+		r.TrimLeadingSpace = true
+		rule, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy line %d: %w", n+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func formatPolicyDocument(rules [][]string) string {
+	var b strings.Builder
+	for _, rule := range rules {
+		for i, field := range rule {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if strings.ContainsAny(field, ",\"\n#") || strings.TrimSpace(field) != field {
+				field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+			}
+			b.WriteString(field)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// fileStore keeps the policy in a local file; the version is a hash of
+// its content. Writes replace the file atomically.
+type fileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileStore) read(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readLocked()
+}
+
+func (s *fileStore) readLocked() (string, string, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return string(data), hex.EncodeToString(sum[:]), nil
+}
+
+func (s *fileStore) write(ctx context.Context, doc, version string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, current, err := s.readLocked(); err != nil {
+		return "", err
+	} else if current != version {
+		return "", errStoreConflict
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".policy-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(doc); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(doc))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// kvStore keeps the policy in an OpenBao KV v2 secret. Each save is a new
+// secret version, so earlier policies can be inspected and rolled back in
+// OpenBao; check-and-set on the version prevents lost updates.
+type kvStore struct {
+	kv   *openbao.KV
+	path string
+}
+
+func (s *kvStore) read(ctx context.Context) (string, string, error) {
+	secret, err := s.kv.Get(ctx, s.path)
+	if errors.Is(err, openbao.ErrNotFound) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	doc, _ := secret.Data["policy"].(string)
+	return doc, strconv.Itoa(secret.Metadata.Version), nil
+}
+
+func (s *kvStore) write(ctx context.Context, doc, version string) (string, error) {
+	cas := 0
+	if version != "" {
+		n, err := strconv.Atoi(version)
+		if err != nil {
+			return "", fmt.Errorf("invalid policy version %q", version)
+		}
+		cas = n
+	}
+	meta, err := s.kv.PutCAS(ctx, s.path, map[string]interface{}{"policy": doc}, cas)
+	if errors.Is(err, openbao.ErrVersionConflict) {
+		return "", errStoreConflict
+	}
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(meta.Version), nil
+}
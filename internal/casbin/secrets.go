@@ -0,0 +1,75 @@
+package casbin
+
+import (
+	"strings"
+
+	"casdoor-casbin-openbao/internal/auth"
+)
+
+// secretPrefix marks secret path objects, e.g. ("user", "secrets:users/{user}/*", "read")
+const secretPrefix = "secrets:"
+
+// Secret path placeholders. A policy on "secrets:users/{user}/*" applies to
+// each user's own folder; "secrets:groups/{group}/*" to the folders of the
+// groups (g2) the user belongs to.
+const (
+	secretUserVar  = "{user}"
+	secretGroupVar = "{group}"
+)
+
+// SecretAllowed reports whether user may perform act (read, list, write or
+// delete) on the secret at path. path is relative to the secrets root, e.g.
+// "users/alice/db"; for list it names a folder. A policy object matches the
+// path exactly or ends in "/*" to cover a folder and everything below it.
+// API keys also need one of the objects in their scopes.
+func SecretAllowed(user *auth.CasdoorClaims, path, act string) (bool, error) {
+	return EnforceUserAny(user, secretObjects(user.Name, path, act == "list"), act)
+}
+
+// secretObjects returns the policy objects that cover path, most specific
+// first, with the caller's own user or group segment also written as its
+// placeholder
+func secretObjects(user, path string, folder bool) []string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	variants := [][]string{segments}
+	if len(segments) >= 2 {
+		switch {
+		case segments[0] == "users" && segments[1] == user:
+			variants = append(variants, withSegment(segments, 1, secretUserVar))
+		case segments[0] == "groups" && inGroup(user, segments[1]):
+			variants = append(variants, withSegment(segments, 1, secretGroupVar))
+		}
+	}
+
+	var objects []string
+	for _, s := range variants {
+		if !folder {
+			objects = append(objects, secretPrefix+strings.Join(s, "/"))
+		}
+		// A folder is covered by "<folder>/*" and by every ancestor's "/*"
+		last := len(s) - 1
+		if folder {
+			last = len(s)
+		}
+		for i := last; i >= 1; i-- {
+			objects = append(objects, secretPrefix+strings.Join(s[:i], "/")+"/*")
+		}
+	}
+	return append(objects, secretPrefix+"*")
+}
+
+func withSegment(segments []string, i int, value string) []string {
+	s := append([]string(nil), segments...)
+	s[i] = value
+	return s
+}
+
+func inGroup(user, group string) bool {
+	for _, g := range GroupsForUser(user) {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+// Package checkout ties orders to the catalog and the ledger: placing an
+// order reserves stock and records a payment, and cancelling or returning
+// it records refunds. Cancelled orders also release their stock.
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+var (
+	// ErrUnknownProduct is returned when ordering a SKU that is not in the active catalog
+	ErrUnknownProduct = errors.New("unknown or inactive product")
+	// ErrInvalidTransition is returned when an order cannot move to the requested status
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrNotRefundable is returned when refunding an order that is not cancelled or returned
+	ErrNotRefundable = errors.New("order is not cancelled or returned")
+	// ErrRefundExceedsPayment is returned when a refund is larger than what remains to be refunded
+	ErrRefundExceedsPayment = errors.New("refund exceeds the amount paid")
+)
+
+// Service places orders and keeps their payments and refunds consistent
+type Service struct {
+	transactor   repository.Transactor
+	orders       repository.OrderRepository
+	products     repository.ProductRepository
+	transactions repository.TransactionRepository
+	ledger       *ledger.Service
+}
+
+// NewService creates a checkout service
+func NewService(transactor repository.Transactor, orders repository.OrderRepository, products repository.ProductRepository, transactions repository.TransactionRepository, ledger *ledger.Service) *Service {
+	return &Service{
+		transactor:   transactor,
+		orders:       orders,
+		products:     products,
+		transactions: transactions,
+		ledger:       ledger,
+	}
+}
+
+// PlaceOrder prices order.SKU from the catalog, reserves order.Quantity
+// units, stores the order and charges its total to the owner's account in
+// one database transaction. Free orders are stored without a payment.
+func (s *Service) PlaceOrder(ctx context.Context, order *model.Order) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := s.products.Reserve(ctx, order.SKU, order.Quantity)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: %q", ErrUnknownProduct, order.SKU)
+			}
+			return err
+		}
+
+		total, err := product.Price.Mul(int64(order.Quantity))
+		if err != nil {
+			return fmt.Errorf("%w: order total is out of range", ledger.ErrInvalidTransaction)
+		}
+		order.ProductName = product.Name
+		order.Price = product.Price
+		order.Total = total
+
+		if order.Total.IsPositive() {
+			payment := s.newOrderTransaction(order, model.TransactionTypePayment, order.Total, order.CreatedBy)
+			payment.Description = "Payment for order " + order.ID
+			if err := s.ledger.Record(ctx, payment); err != nil {
+				return err
+			}
+			order.PaymentID = payment.ID
+		}
+		return s.orders.Create(ctx, order)
+	})
+}
+
+// ChangeStatus moves an order from one status to another. Cancelling or
+// returning a paid order refunds amount, or everything not yet refunded
+// when amount is nil; cancelling also releases the reserved stock. It
+// returns the updated order and the refund, if any.
+func (s *Service) ChangeStatus(ctx context.Context, id, from, to, changedBy string, amount *money.Money) (*model.Order, *model.Transaction, error) {
+	var updated *model.Order
+	var refund *model.Transaction
+
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		order, err := s.orders.Lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != from {
+			return repository.ErrConflict
+		}
+		if !model.CanTransitionOrder(from, to) {
+			return ErrInvalidTransition
+		}
+
+		// Validate the refund before changing anything so a bad amount
+		// leaves the order untouched
+		var refundAmount money.Money
+		refunding := isRefundable(to) && order.PaymentID != ""
+		if refunding {
+			if refundAmount, err = s.refundAmount(ctx, order, amount); err != nil {
+				return err
+			}
+		} else if amount != nil {
+			return fmt.Errorf("%w: only cancelled or returned orders with a payment can be refunded", ErrNotRefundable)
+		}
+
+		if updated, err = s.orders.UpdateStatus(ctx, id, from, to, changedBy); err != nil {
+			return err
+		}
+		if to == model.OrderStatusCancelled && order.SKU != "" {
+			if err := s.products.Release(ctx, order.SKU, order.Quantity); err != nil {
+				return err
+			}
+		}
+		if refunding {
+			refund, err = s.recordRefund(ctx, updated, refundAmount, changedBy)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, refund, nil
+}
+
+// Refund records a further refund for a cancelled or returned order
+func (s *Service) Refund(ctx context.Context, id, refundedBy string, amount *money.Money) (*model.Transaction, error) {
+	var refund *model.Transaction
+
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		order, err := s.orders.Lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !isRefundable(order.Status) || order.PaymentID == "" {
+			return ErrNotRefundable
+		}
+		refundAmount, err := s.refundAmount(ctx, order, amount)
+		if err != nil {
+			return err
+		}
+		refund, err = s.recordRefund(ctx, order, refundAmount, refundedBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// Transactions returns the payment and refunds linked to an order
+func (s *Service) Transactions(ctx context.Context, orderID string) ([]model.Transaction, error) {
+	return s.transactions.ListByOrder(ctx, orderID)
+}
+
+// Refundable returns how much of an order's payment has not been refunded yet
+func (s *Service) Refundable(ctx context.Context, order *model.Order) (money.Money, error) {
+	remaining := money.Zero(order.Total.Currency)
+
+	txns, err := s.transactions.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return remaining, err
+	}
+	for _, txn := range txns {
+		if txn.Status != model.TransactionStatusCompleted {
+			continue
+		}
+		switch txn.Type {
+		case model.TransactionTypePayment:
+			remaining, err = remaining.Add(txn.Amount)
+		case model.TransactionTypeRefund:
+			remaining, err = remaining.Sub(txn.Amount)
+		}
+		if err != nil {
+			return remaining, err
+		}
+	}
+	return remaining, nil
+}
+
+// refundAmount checks a requested refund against what remains to be
+// refunded; nil requests the full remainder. The caller must hold the
+// order lock so concurrent refunds cannot both pass the check.
+func (s *Service) refundAmount(ctx context.Context, order *model.Order, amount *money.Money) (money.Money, error) {
+	remaining, err := s.Refundable(ctx, order)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if amount == nil {
+		if !remaining.IsPositive() {
+			return money.Money{}, fmt.Errorf("%w: order is already fully refunded", ErrRefundExceedsPayment)
+		}
+		return remaining, nil
+	}
+
+	if !amount.IsPositive() {
+		return money.Money{}, fmt.Errorf("%w: refund amount must be positive", ledger.ErrInvalidTransaction)
+	}
+	cmp, err := amount.Cmp(remaining)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: refund must be in %s", ledger.ErrInvalidTransaction, remaining.Currency)
+	}
+	if cmp > 0 {
+		return money.Money{}, fmt.Errorf("%w: at most %s %s can be refunded", ErrRefundExceedsPayment, remaining, remaining.Currency)
+	}
+	return *amount, nil
+}
+
+// recordRefund posts a refund of amount from the merchant to the order owner
+func (s *Service) recordRefund(ctx context.Context, order *model.Order, amount money.Money, refundedBy string) (*model.Transaction, error) {
+	refund := s.newOrderTransaction(order, model.TransactionTypeRefund, amount, refundedBy)
+	refund.Description = fmt.Sprintf("Refund for %s order %s", order.Status, order.ID)
+	if err := s.ledger.Record(ctx, refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (s *Service) newOrderTransaction(order *model.Order, typ string, amount money.Money, createdBy string) *model.Transaction {
+	return &model.Transaction{
+		ID:        model.NewID("txn"),
+		UserID:    order.UserID,
+		Amount:    amount,
+		Type:      typ,
+		Status:    model.TransactionStatusPending,
+		OrderID:   order.ID,
+		CreatedAt: time.Now(),
+		CreatedBy: createdBy,
+	}
+}
+
+// isRefundable reports whether an order in status may be refunded
+func isRefundable(status string) bool {
+	return status == model.OrderStatusCancelled || status == model.OrderStatusReturned
+}
@@ -0,0 +1,171 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+type fixture struct {
+	service  *Service
+	accounts repository.AccountRepository
+	products repository.ProductRepository
+}
+
+// newFixture runs the service on the in-memory repositories with one
+// product, LAPTOP at 100.00 USD with 5 in stock, and alice holding 250.00 USD
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	transactor := repository.NewMemoryTransactor()
+	accounts := repository.NewMemoryAccountRepository()
+	transactions := repository.NewMemoryTransactionRepository()
+	products := repository.NewMemoryProductRepository(model.Product{
+		ID: "prod_1", SKU: "LAPTOP", Name: "Laptop", Price: usd(10000), Stock: 5, Active: true,
+	})
+	ledgerService := ledger.NewService(transactor, accounts, transactions, ledger.ApprovalPolicy{})
+
+	deposit := &model.Transaction{ID: model.NewID("txn"), UserID: "alice", Type: model.TransactionTypeDeposit, Amount: usd(25000), CreatedBy: "alice", CreatedAt: time.Now()}
+	if err := ledgerService.Record(ctx, deposit); err != nil {
+		t.Fatal(err)
+	}
+
+	return &fixture{
+		service:  NewService(transactor, repository.NewMemoryOrderRepository(), products, transactions, ledgerService),
+		accounts: accounts,
+		products: products,
+	}
+}
+
+func usd(minor int64) money.Money {
+	return money.Money{Minor: minor, Currency: "USD"}
+}
+
+func (f *fixture) balance(t *testing.T, owner string) int64 {
+	t.Helper()
+	account, err := f.accounts.GetOrCreate(context.Background(), owner, model.AccountKindUser, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account.Balance.Minor
+}
+
+func (f *fixture) stock(t *testing.T) int {
+	t.Helper()
+	product, err := f.products.Get(context.Background(), "prod_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Stock
+}
+
+func newOrder(quantity int) *model.Order {
+	return &model.Order{
+		ID:        model.NewID("ord"),
+		UserID:    "alice",
+		SKU:       "LAPTOP",
+		Quantity:  quantity,
+		Status:    model.OrderStatusPending,
+		CreatedBy: "alice",
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestPlaceAndCancelOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	order := newOrder(2)
+	if err := f.service.PlaceOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Total != usd(20000) || order.PaymentID == "" {
+		t.Errorf("placed order: total %v, payment %q; want 200.00 USD and a payment", order.Total, order.PaymentID)
+	}
+	if stock := f.stock(t); stock != 3 {
+		t.Errorf("stock after ordering 2 = %d, want 3", stock)
+	}
+	if balance := f.balance(t, "alice"); balance != 5000 {
+		t.Errorf("balance after paying = %d, want 5000", balance)
+	}
+
+	updated, refund, err := f.service.ChangeStatus(ctx, order.ID, model.OrderStatusPending, model.OrderStatusCancelled, "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != model.OrderStatusCancelled || refund == nil || refund.Amount != usd(20000) {
+		t.Errorf("cancel: status %q, refund %+v; want cancelled with a full refund", updated.Status, refund)
+	}
+	if stock := f.stock(t); stock != 5 {
+		t.Errorf("stock after cancelling = %d, want 5", stock)
+	}
+	if balance := f.balance(t, "alice"); balance != 25000 {
+		t.Errorf("balance after the refund = %d, want 25000", balance)
+	}
+
+	// Nothing is left to refund
+	if _, err := f.service.Refund(ctx, order.ID, "admin", nil); !errors.Is(err, ErrRefundExceedsPayment) {
+		t.Errorf("second refund: err = %v, want ErrRefundExceedsPayment", err)
+	}
+}
+
+func TestPlaceOrderRejected(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	if err := f.service.PlaceOrder(ctx, newOrder(6)); !errors.Is(err, repository.ErrOutOfStock) {
+		t.Errorf("ordering 6 of 5: err = %v, want ErrOutOfStock", err)
+	}
+	unknown := newOrder(1)
+	unknown.SKU = "PHONE"
+	if err := f.service.PlaceOrder(ctx, unknown); !errors.Is(err, ErrUnknownProduct) {
+		t.Errorf("ordering an unknown SKU: err = %v, want ErrUnknownProduct", err)
+	}
+	if stock := f.stock(t); stock != 5 {
+		t.Errorf("stock after rejected orders = %d, want 5", stock)
+	}
+	if balance := f.balance(t, "alice"); balance != 25000 {
+		t.Errorf("balance after rejected orders = %d, want 25000", balance)
+	}
+}
+
+func TestPartialRefunds(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	order := newOrder(1)
+	if err := f.service.PlaceOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.Refund(ctx, order.ID, "admin", nil); !errors.Is(err, ErrNotRefundable) {
+		t.Errorf("refunding a pending order: err = %v, want ErrNotRefundable", err)
+	}
+
+	partial := usd(4000)
+	if _, _, err := f.service.ChangeStatus(ctx, order.ID, model.OrderStatusPending, model.OrderStatusCancelled, "admin", &partial); err != nil {
+		t.Fatal(err)
+	}
+	tooMuch := usd(6001)
+	if _, err := f.service.Refund(ctx, order.ID, "admin", &tooMuch); !errors.Is(err, ErrRefundExceedsPayment) {
+		t.Errorf("refunding more than was paid: err = %v, want ErrRefundExceedsPayment", err)
+	}
+	if _, err := f.service.Refund(ctx, order.ID, "admin", nil); err != nil {
+		t.Fatal(err)
+	}
+	if balance := f.balance(t, "alice"); balance != 25000 {
+		t.Errorf("balance after refunding everything = %d, want 25000", balance)
+	}
+	remaining, err := f.service.Refundable(ctx, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !remaining.IsZero() {
+		t.Errorf("refundable after refunding everything = %v, want zero", remaining)
+	}
+}
@@ -2,19 +2,31 @@ package config
 
 // Re-export config from root config package
 import (
+	"sync/atomic"
+
 	rootConfig "casdoor-casbin-openbao/config"
 )
 
 // Config re-exports the Config type from root config package
 type Config = rootConfig.Config
 
-var AppConfig *Config
+// CasbinConfig re-exports the Casbin section of Config
+type CasbinConfig = rootConfig.CasbinConfig
+
+// MTLSConfig re-exports the MTLS section of Config
+type MTLSConfig = rootConfig.MTLSConfig
+
+// LoginLimitConfig re-exports the Login section of Config
+type LoginLimitConfig = rootConfig.LoginLimitConfig
+
+// appConfig is replaced as a whole when secrets are refreshed, so callers
+// of GetConfig never see a half-updated value
+var appConfig atomic.Pointer[Config]
 
 func Init() {
-	AppConfig = rootConfig.LoadConfig()
+	appConfig.Store(rootConfig.LoadConfig())
 }
 
 func GetConfig() *Config {
-	return AppConfig
+	return appConfig.Load()
 }
-
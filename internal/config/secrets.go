@@ -0,0 +1,250 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	rootConfig "casdoor-casbin-openbao/config"
+	"casdoor-casbin-openbao/internal/openbao"
+)
+
+// minRefresh keeps a very short lease from turning the refresh loop into
+// a busy loop
+const minRefresh = 5 * time.Second
+
+var baoClient *openbao.Client
+
+// OpenBao returns the logged-in OpenBao client, or nil when OPENBAO_ADDR
+// is not set. LoadSecrets must have succeeded.
+func OpenBao() *openbao.Client {
+	return baoClient
+}
+
+// LoadSecrets logs in to OpenBao and replaces every bao:// reference in the
+// configuration (see Config.SecretFields) with the value it points at. It
+// fails when a reference cannot be resolved or a required value is empty,
+// so the server never starts half-configured. Afterwards the token and
+// secret leases are renewed in the background until ctx is done, and each
+// renewal re-reads the references.
+func LoadSecrets(ctx context.Context) error {
+	cfg := GetConfig()
+
+	var refs []*secretRef
+	for _, field := range cfg.SecretFields() {
+		if !openbao.IsRef(*field.Value) {
+			continue
+		}
+		ref, err := openbao.ParseRef(*field.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Env, err)
+		}
+		refs = append(refs, &secretRef{env: field.Env, ref: ref})
+	}
+
+	if cfg.OpenBao.Address == "" {
+		if len(refs) > 0 {
+			return fmt.Errorf("%s is a %s reference but OPENBAO_ADDR is not set", refs[0].env, openbao.RefScheme)
+		}
+		return checkRequired(cfg)
+	}
+
+	w := &secretWatcher{cfg: cfg.OpenBao, refs: refs}
+	if err := w.connect(ctx); err != nil {
+		return err
+	}
+	values := map[string]string{}
+	for _, r := range refs {
+		if err := w.resolve(ctx, r); err != nil {
+			return fmt.Errorf("%s: %w", r.env, err)
+		}
+		values[r.env] = r.value
+	}
+	if err := checkRequired(apply(values)); err != nil {
+		return err
+	}
+	if len(refs) > 0 {
+		log.Printf("Resolved %d configuration secrets from OpenBao", len(refs))
+	}
+
+	baoClient = w.client
+	go w.run(ctx)
+	return nil
+}
+
+func checkRequired(cfg *Config) error {
+	for _, field := range cfg.SecretFields() {
+		if field.Required && *field.Value == "" {
+			return fmt.Errorf("%s is not set (use a value or a %s reference)", field.Env, openbao.RefScheme)
+		}
+	}
+	return nil
+}
+
+// apply stores a copy of the configuration with values, keyed by
+// environment variable, replaced
+func apply(values map[string]string) *Config {
+	next := *GetConfig()
+	for _, field := range next.SecretFields() {
+		if value, ok := values[field.Env]; ok {
+			*field.Value = value
+		}
+	}
+	appConfig.Store(&next)
+	return &next
+}
+
+// secretRef is a resolved bao:// reference
+type secretRef struct {
+	env   string
+	ref   openbao.Ref
+	value string
+	lease openbao.Lease
+}
+
+// secretWatcher keeps the OpenBao token and the resolved references fresh
+type secretWatcher struct {
+	cfg    rootConfig.OpenBaoConfig
+	client *openbao.Client
+	token  openbao.TokenInfo
+	refs   []*secretRef
+}
+
+// connect creates the client and logs in with the configured method
+func (w *secretWatcher) connect(ctx context.Context) error {
+	client, err := openbao.NewClient(openbao.Config{Address: w.cfg.Address, Token: w.cfg.Token, Namespace: w.cfg.Namespace})
+	if err != nil {
+		return err
+	}
+	w.client = client
+	if err := w.login(ctx); err != nil {
+		return fmt.Errorf("OpenBao login failed: %w", err)
+	}
+	return nil
+}
+
+func (w *secretWatcher) login(ctx context.Context) error {
+	method := w.cfg.AuthMethod
+	if method == "" {
+		method = "token"
+		if w.cfg.RoleID != "" {
+			method = "approle"
+		}
+	}
+
+	var info *openbao.TokenInfo
+	var err error
+	switch method {
+	case "approle":
+		if w.cfg.RoleID == "" || w.cfg.SecretID == "" {
+			return errors.New("OPENBAO_ROLE_ID and OPENBAO_SECRET_ID are required for approle")
+		}
+		info, err = w.client.LoginAppRole(ctx, w.cfg.AppRoleMount, w.cfg.RoleID, w.cfg.SecretID)
+	case "token":
+		if w.client.Token() == "" {
+			return errors.New("OPENBAO_TOKEN is required for token auth")
+		}
+		info, err = w.client.LookupSelf(ctx)
+	default:
+		return fmt.Errorf("unknown OPENBAO_AUTH_METHOD %q (use token or approle)", method)
+	}
+	if err != nil {
+		return err
+	}
+	w.token = *info
+	return nil
+}
+
+func (w *secretWatcher) resolve(ctx context.Context, r *secretRef) error {
+	value, lease, err := w.client.Resolve(ctx, r.ref)
+	if err != nil {
+		return err
+	}
+	r.value, r.lease = value, lease
+	return nil
+}
+
+func (w *secretWatcher) run(ctx context.Context) {
+	timer := time.NewTimer(w.next())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		w.refresh(ctx)
+		timer.Reset(w.next())
+	}
+}
+
+// next is the wait until the next refresh: two thirds of the shortest
+// lease, and never longer than RefreshInterval
+func (w *secretWatcher) next() time.Duration {
+	wait := w.cfg.RefreshInterval
+	leases := []openbao.Lease{w.token.Lease}
+	for _, r := range w.refs {
+		leases = append(leases, r.lease)
+	}
+	for _, lease := range leases {
+		if d := lease.Duration * 2 / 3; lease.Duration > 0 && d < wait {
+			wait = d
+		}
+	}
+	if wait < minRefresh {
+		wait = minRefresh
+	}
+	return wait
+}
+
+// refresh renews the token and leases and re-reads the references. Errors
+// are logged and the previous values stay in use.
+func (w *secretWatcher) refresh(ctx context.Context) {
+	if lease := w.token.Lease; lease.Duration > 0 {
+		// Renew the token; log in again once it stops renewing (max TTL)
+		renewed := false
+		if lease.Renewable {
+			info, err := w.client.RenewSelf(ctx, 0)
+			if err == nil && info.Lease.Duration >= lease.Duration/2 {
+				w.token, renewed = *info, true
+			}
+		}
+		if !renewed {
+			if err := w.login(ctx); err != nil {
+				log.Printf("Warning: OpenBao token renewal failed: %v", err)
+				return
+			}
+		}
+	}
+
+	changed := map[string]string{}
+	for _, r := range w.refs {
+		// A leased value stays valid while its lease can be renewed; once
+		// it cannot (or it has no lease, like KV), it is read again
+		if r.lease.ID != "" && r.lease.Renewable {
+			lease, err := w.client.RenewLease(ctx, r.lease.ID, r.lease.Duration)
+			if err == nil && lease.Duration >= r.lease.Duration/2 {
+				r.lease.Duration = lease.Duration
+				continue
+			}
+		}
+
+		previous := r.value
+		if err := w.resolve(ctx, r); err != nil {
+			log.Printf("Warning: failed to refresh %s from OpenBao: %v", r.env, err)
+			continue
+		}
+		if r.value != previous {
+			changed[r.env] = r.value
+		}
+	}
+
+	if len(changed) > 0 {
+		apply(changed)
+		for env := range changed {
+			log.Printf("Configuration secret %s refreshed from OpenBao", env)
+		}
+	}
+}
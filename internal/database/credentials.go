@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/metrics"
+	"casdoor-casbin-openbao/internal/openbao"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxIdleConns matches database/sql's default idle pool size
+	maxIdleConns = 2
+	// rotationRetry is the wait after a failed renewal or rotation
+	rotationRetry = 10 * time.Second
+	// revokeDelay is how long busy connections of rotated credentials may
+	// keep running before their lease is revoked
+	revokeDelay = time.Minute
+)
+
+var (
+	leaseTTL = metrics.NewGaugeFunc("db_credentials_lease_ttl_seconds",
+		"Seconds until the current database credentials lease expires", func() float64 {
+			if rotator == nil {
+				return 0
+			}
+			return rotator.remaining().Seconds()
+		})
+	rotations = metrics.NewCounter("db_credentials_rotations_total",
+		"Database credential rotations")
+	rotationFailures = metrics.NewCounter("db_credentials_rotation_failures_total",
+		"Failed attempts to get new database credentials")
+	revokeFailures = metrics.NewCounter("db_credentials_revoke_failures_total",
+		"Failed revocations of rotated database credentials")
+	renewalFailures = metrics.NewCounter("db_credentials_renewal_failures_total",
+		"Failed database credentials lease renewals")
+)
+
+var rotator *credentialRotator
+
+// initDynamicDB connects with credentials from OpenBao's database secrets
+// engine. New connections always use the current credentials; a rotation
+// retires pooled connections of earlier credentials as they come back to
+// the pool, so requests in flight finish on the connection they started on.
+func initDynamicDB() error {
+	cfg := config.GetConfig()
+	client := config.OpenBao()
+	if client == nil {
+		return fmt.Errorf("DB_OPENBAO_ROLE requires OPENBAO_ADDR")
+	}
+
+	r := &credentialRotator{client: client, mount: cfg.Database.CredentialsMount, role: cfg.Database.CredentialsRole}
+	if err := r.rotate(context.Background()); err != nil {
+		return fmt.Errorf("failed to get database credentials: %w", err)
+	}
+
+	connConfig, err := pgx.ParseConfig(fmt.Sprintf("host=%s dbname=%s port=%s sslmode=disable",
+		cfg.Database.Host,
+		cfg.Database.DBName,
+		cfg.Database.Port,
+	))
+	if err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
+	}
+	r.db = stdlib.OpenDB(*connConfig,
+		stdlib.OptionBeforeConnect(r.beforeConnect),
+		stdlib.OptionResetSession(r.resetSession),
+	)
+	r.db.SetMaxIdleConns(maxIdleConns)
+
+	DB, err = gorm.Open(postgres.New(postgres.Config{Conn: r.db}), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	rotator = r
+	go r.run(context.Background())
+	log.Printf("Database connected with OpenBao credentials (role %s, lease %s)", r.role, r.lease.Duration)
+	return nil
+}
+
+// credentialRotator keeps the pool's database credentials valid: it renews
+// the lease and switches to new credentials before the lease runs out
+type credentialRotator struct {
+	client *openbao.Client
+	mount  string
+	role   string
+	db     *sql.DB
+
+	mu       sync.RWMutex
+	username string
+	password string
+	lease    openbao.Lease
+	ttl      time.Duration // lease duration when the credentials were issued
+	expires  time.Time
+}
+
+func (r *credentialRotator) credentials() (string, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.username, r.password
+}
+
+func (r *credentialRotator) remaining() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if d := time.Until(r.expires); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// beforeConnect puts the current credentials on each new connection
+func (r *credentialRotator) beforeConnect(ctx context.Context, cc *pgx.ConnConfig) error {
+	cc.User, cc.Password = r.credentials()
+	return nil
+}
+
+// resetSession discards a pooled connection opened with credentials that
+// have since been rotated; database/sql then uses or opens another one
+func (r *credentialRotator) resetSession(ctx context.Context, conn *pgx.Conn) error {
+	if user, _ := r.credentials(); conn.Config().User != user {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (r *credentialRotator) run(ctx context.Context) {
+	wait := r.next()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = r.next()
+		if err := r.renewOrRotate(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+			if wait > rotationRetry {
+				wait = rotationRetry
+			}
+		}
+	}
+}
+
+// next is the wait until the next renewal: two thirds of the lease
+func (r *credentialRotator) next() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	wait := r.lease.Duration * 2 / 3
+	if wait < rotationRetry/2 {
+		wait = rotationRetry / 2
+	}
+	return wait
+}
+
+// renewOrRotate extends the lease while it can still be extended by at
+// least half its original duration, and rotates credentials otherwise
+func (r *credentialRotator) renewOrRotate(ctx context.Context) error {
+	r.mu.RLock()
+	lease, ttl := r.lease, r.ttl
+	r.mu.RUnlock()
+
+	if lease.Renewable {
+		renewed, err := r.client.RenewLease(ctx, lease.ID, ttl)
+		if err == nil && renewed.Duration >= ttl/2 {
+			r.mu.Lock()
+			r.lease.Duration = renewed.Duration
+			r.expires = time.Now().Add(renewed.Duration)
+			r.mu.Unlock()
+			return nil
+		}
+		if err != nil {
+			renewalFailures.Inc()
+			log.Printf("Warning: database credentials renewal failed, rotating: %v", err)
+		}
+	}
+
+	if err := r.rotate(ctx); err != nil {
+		rotationFailures.Inc()
+		return fmt.Errorf("database credentials rotation failed (current lease ends in %s): %w", r.remaining().Round(time.Second), err)
+	}
+	return nil
+}
+
+// rotate switches to new credentials. Idle connections are closed right
+// away; busy ones are discarded when they are returned. The previous lease
+// is revoked after revokeDelay, so its database role does not outlive the
+// rotation until its TTL runs out.
+func (r *credentialRotator) rotate(ctx context.Context) error {
+	creds, err := r.client.DatabaseCredentials(ctx, r.mount, r.role)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	first := r.username == ""
+	previous := r.lease
+	r.username, r.password = creds.Username, creds.Password
+	r.lease, r.ttl = creds.Lease, creds.Lease.Duration
+	r.expires = time.Now().Add(creds.Lease.Duration)
+	r.mu.Unlock()
+
+	if first {
+		return nil
+	}
+	r.db.SetMaxIdleConns(0)
+	r.db.SetMaxIdleConns(maxIdleConns)
+	rotations.Inc()
+	log.Printf("Database credentials rotated (lease %s)", creds.Lease.Duration)
+	if previous.ID != "" {
+		time.AfterFunc(revokeDelay, func() { r.revoke(previous.ID) })
+	}
+	return nil
+}
+
+// revoke revokes the lease of rotated credentials
+func (r *credentialRotator) revoke(leaseID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), rotationRetry)
+	defer cancel()
+	if err := r.client.RevokeLease(ctx, leaseID); err != nil {
+		revokeFailures.Inc()
+		log.Printf("Warning: failed to revoke rotated database credentials (they expire with their lease): %v", err)
+	}
+}
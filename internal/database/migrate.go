@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// Migrate creates or updates the application tables
+func Migrate() error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if err := DB.AutoMigrate(
+		&model.Product{},
+		&model.Order{},
+		&model.OrderStatusHistory{},
+		&model.Transaction{},
+		&model.Account{},
+		&model.LedgerEntry{},
+		&model.IdempotencyRecord{},
+		&model.ServiceAccount{},
+		&model.APIKey{},
+		&model.LoginFailure{},
+	); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	log.Println("Database migrated successfully")
+	return nil
+}
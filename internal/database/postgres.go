@@ -13,6 +13,10 @@ var DB *gorm.DB
 
 func InitDB() error {
 	cfg := config.GetConfig()
+	if cfg.Database.CredentialsRole != "" {
+		return initDynamicDB()
+	}
+
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		cfg.Database.Host,
 		cfg.Database.User,
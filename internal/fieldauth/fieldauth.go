@@ -0,0 +1,270 @@
+// Package fieldauth applies field-level Casbin permissions to API
+// payloads.
+//
+// Protected fields are checked as (<resource>.<field>, read|write), with
+// (<resource>.*, read|write) granting every field of a resource. Fields
+// that are not protected are always readable and writable; the route and
+// record checks still apply to them.
+//
+// When encrypted fields are only decrypted for authorized callers (see
+// fieldcrypt.DecryptAuthorized), they also need (<resource>.<field>,
+// decrypt); without it they are masked.
+//
+// API keys only get the field permissions in their scopes, e.g.
+// "orders.total:read" or "transactions.*:read".
+package fieldauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/fieldcrypt"
+)
+
+// Actions
+const (
+	Read    = "read"
+	Write   = "write"
+	Decrypt = "decrypt"
+)
+
+// Mode is how an unreadable field is hidden
+type Mode int
+
+const (
+	// Drop removes the field
+	Drop Mode = iota
+	// Mask replaces a string field with Masked; other values are dropped
+	Mask
+)
+
+// Masked replaces masked string values
+const Masked = "***"
+
+// Field is a protected field, named by its JSON key or export column
+type Field struct {
+	Name string
+	Mode Mode
+}
+
+// readProtected lists the fields whose reads are checked
+var readProtected = map[string][]Field{
+	"orders":       {{"price", Drop}, {"total", Drop}},
+	"transactions": {{"amount", Drop}, {"description", Mask}, {"counterparty", Mask}},
+}
+
+// encrypted lists the fields stored with the transit serializer (see the
+// model tags)
+var encrypted = map[string][]string{
+	"orders":       {"shipping_address"},
+	"transactions": {"description"},
+}
+
+// writeProtected lists the request fields whose writes are checked.
+// "status" is the status an order or transaction is moved to, and
+// "refund_amount" also covers the amount of POST /api/orders/:id/refunds.
+var writeProtected = map[string][]string{
+	"products":     {"price", "stock", "active"},
+	"orders":       {"quantity", "shipping_address", "status", "refund_amount"},
+	"transactions": {"amount", "type", "description", "counterparty", "status"},
+}
+
+// ErrForbiddenFields is returned when a request sets fields the caller may
+// not write
+var ErrForbiddenFields = errors.New("not allowed to set fields")
+
+// ForbiddenFieldsError lists the fields the caller may not write
+type ForbiddenFieldsError struct {
+	Fields []string
+}
+
+func (e *ForbiddenFieldsError) Error() string {
+	return ErrForbiddenFields.Error() + ": " + strings.Join(e.Fields, ", ")
+}
+
+func (e *ForbiddenFieldsError) Unwrap() error {
+	return ErrForbiddenFields
+}
+
+// Allowed reports whether user may perform act on a field of resource
+func Allowed(user *auth.CasdoorClaims, resource, field, act string) (bool, error) {
+	return casbin.EnforceUserAny(user, []string{resource + "." + field, resource + ".*"}, act)
+}
+
+// CheckWrite returns a ForbiddenFieldsError naming the protected fields
+// among fields that user may not write on resource
+func CheckWrite(user *auth.CasdoorClaims, resource string, fields ...string) error {
+	var forbidden []string
+	for _, field := range fields {
+		if !contains(writeProtected[resource], field) {
+			continue
+		}
+		ok, err := Allowed(user, resource, field, Write)
+		if err != nil {
+			return fmt.Errorf("field authorization check failed: %w", err)
+		}
+		if !ok {
+			forbidden = append(forbidden, field)
+		}
+	}
+	if forbidden != nil {
+		return &ForbiddenFieldsError{Fields: forbidden}
+	}
+	return nil
+}
+
+// Shaper hides the fields of one resource that one caller may not read
+// and decrypts the encrypted ones they may decrypt. Permissions are
+// evaluated once, when the Shaper is created; it belongs to one request.
+type Shaper struct {
+	ctx     context.Context
+	hidden  map[string]Mode
+	decrypt map[string]bool
+}
+
+// For evaluates user's read permissions on the protected fields of resource
+func For(ctx context.Context, user *auth.CasdoorClaims, resource string) (*Shaper, error) {
+	s := &Shaper{ctx: ctx, hidden: map[string]Mode{}, decrypt: map[string]bool{}}
+	for _, f := range readProtected[resource] {
+		ok, err := Allowed(user, resource, f.Name, Read)
+		if err != nil {
+			return nil, fmt.Errorf("field authorization check failed: %w", err)
+		}
+		if !ok {
+			s.hidden[f.Name] = f.Mode
+		}
+	}
+
+	if fieldcrypt.DecryptOnLoad() {
+		return s, nil
+	}
+	for _, name := range encrypted[resource] {
+		if _, hidden := s.hidden[name]; hidden {
+			continue
+		}
+		ok, err := Allowed(user, resource, name, Decrypt)
+		if err != nil {
+			return nil, fmt.Errorf("field authorization check failed: %w", err)
+		}
+		if ok {
+			s.decrypt[name] = true
+		} else {
+			s.hidden[name] = Mask
+		}
+	}
+	return s, nil
+}
+
+// CanRead reports whether field is readable
+func (s *Shaper) CanRead(field string) bool {
+	_, hidden := s.hidden[field]
+	return !hidden
+}
+
+// Hidden returns the names of the fields the caller cannot read
+func (s *Shaper) Hidden() []string {
+	var names []string
+	for name := range s.hidden {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Apply returns v with unreadable fields hidden and decryptable fields
+// decrypted. v is a struct, a pointer to one or a slice of them. When
+// there is nothing to change v is returned as is; otherwise it is
+// converted to its JSON object form.
+func (s *Shaper) Apply(v interface{}) (interface{}, error) {
+	if (len(s.hidden) == 0 && len(s.decrypt) == 0) || v == nil {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	switch value := generic.(type) {
+	case map[string]interface{}:
+		err = s.shape(value)
+	case []interface{}:
+		for _, item := range value {
+			if obj, ok := item.(map[string]interface{}); ok && err == nil {
+				err = s.shape(obj)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+func (s *Shaper) shape(obj map[string]interface{}) error {
+	for name, mode := range s.hidden {
+		value, ok := obj[name]
+		if !ok {
+			continue
+		}
+		if _, isString := value.(string); mode == Mask && isString {
+			obj[name] = Masked
+		} else {
+			delete(obj, name)
+		}
+	}
+	for name := range s.decrypt {
+		if value, ok := obj[name].(string); ok {
+			plaintext, err := fieldcrypt.Decrypt(s.ctx, value)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", name, err)
+			}
+			obj[name] = plaintext
+		}
+	}
+	return nil
+}
+
+// Row hides unreadable cells of a tabular row whose header is columns and
+// decrypts decryptable ones. Cells are blanked or masked rather than
+// removed so columns stay aligned.
+func (s *Shaper) Row(columns, row []string) ([]string, error) {
+	for i, column := range columns {
+		if i >= len(row) {
+			break
+		}
+		if mode, hidden := s.hidden[column]; hidden {
+			if mode == Mask && row[i] != "" {
+				row[i] = Masked
+			} else {
+				row[i] = ""
+			}
+			continue
+		}
+		if s.decrypt[column] {
+			plaintext, err := fieldcrypt.Decrypt(s.ctx, row[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", column, err)
+			}
+			row[i] = plaintext
+		}
+	}
+	return row, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
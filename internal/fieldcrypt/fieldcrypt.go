@@ -0,0 +1,191 @@
+// Package fieldcrypt encrypts selected GORM fields with OpenBao's transit
+// engine. A string field opts in with the tag `gorm:"serializer:transit"`;
+// its column then holds a transit ciphertext ("vault:v1:...").
+//
+// Until Configure is called the serializer stores plain text, so the
+// models work without OpenBao. Values written before encryption was
+// enabled are encrypted by Rewrap.
+package fieldcrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"casdoor-casbin-openbao/internal/openbao"
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the GORM serializer of encrypted fields
+const SerializerName = "transit"
+
+// Mode decides where ciphertexts are decrypted
+type Mode string
+
+const (
+	// DecryptAlways decrypts when rows are loaded; every reader of the
+	// model sees plain text
+	DecryptAlways Mode = "always"
+	// DecryptAuthorized keeps ciphertexts in loaded models; responses
+	// decrypt a field only for callers with (<resource>.<field>, decrypt)
+	DecryptAuthorized Mode = "authorized"
+)
+
+// ErrNotConfigured is returned when encryption is needed but disabled
+var ErrNotConfigured = errors.New("field encryption is not configured")
+
+type engine struct {
+	transit *openbao.Transit
+	key     string
+	mode    Mode
+}
+
+var current atomic.Pointer[engine]
+
+func init() {
+	schema.RegisterSerializer(SerializerName, serializer{})
+}
+
+// Configure encrypts fields with key of the transit engine from now on. A
+// nil transit turns encryption off.
+func Configure(transit *openbao.Transit, key string, mode Mode) error {
+	if transit == nil {
+		current.Store(nil)
+		return nil
+	}
+	if mode != DecryptAlways && mode != DecryptAuthorized {
+		return fmt.Errorf("unknown decrypt mode %q (use always or authorized)", mode)
+	}
+	current.Store(&engine{transit: transit, key: key, mode: mode})
+	return nil
+}
+
+// Enabled reports whether fields are encrypted
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// DecryptOnLoad reports whether loaded models hold plain text. It is false
+// in DecryptAuthorized mode.
+func DecryptOnLoad() bool {
+	e := current.Load()
+	return e == nil || e.mode == DecryptAlways
+}
+
+// Status describes the encryption key in use
+type Status struct {
+	Key                  string `json:"key"`
+	Mode                 Mode   `json:"mode"`
+	LatestVersion        int    `json:"latest_version"`
+	MinDecryptionVersion int    `json:"min_decryption_version"`
+}
+
+// KeyStatus reads the current versions of the encryption key
+func KeyStatus(ctx context.Context) (*Status, error) {
+	e := current.Load()
+	if e == nil {
+		return nil, ErrNotConfigured
+	}
+	key, err := e.transit.Key(ctx, e.key)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{
+		Key:                  e.key,
+		Mode:                 e.mode,
+		LatestVersion:        key.LatestVersion,
+		MinDecryptionVersion: key.MinDecryptionVersion,
+	}, nil
+}
+
+// IsCiphertext reports whether s is a transit ciphertext
+func IsCiphertext(s string) bool {
+	return openbao.CiphertextVersion(s) > 0
+}
+
+type storedKey struct{}
+
+// KeepCiphertexts marks ctx for writing back models that were loaded from
+// the database: ciphertexts kept in DecryptAuthorized mode are stored as
+// they are instead of being encrypted again. Only repositories saving
+// loaded rows may use it; values from requests are always encrypted, even
+// when they look like ciphertexts.
+func KeepCiphertexts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, storedKey{}, true)
+}
+
+func keepsCiphertexts(ctx context.Context) bool {
+	keep, _ := ctx.Value(storedKey{}).(bool)
+	return keep
+}
+
+// Encrypt returns the ciphertext of s for values stored outside the
+// serializer. s is returned as it is while encryption is off.
+func Encrypt(ctx context.Context, s string) (string, error) {
+	e := current.Load()
+	if e == nil || s == "" {
+		return s, nil
+	}
+	return e.transit.Encrypt(ctx, e.key, s)
+}
+
+// Decrypt returns the plain text of s; values that are not ciphertexts are
+// returned as they are
+func Decrypt(ctx context.Context, s string) (string, error) {
+	if !IsCiphertext(s) {
+		return s, nil
+	}
+	e := current.Load()
+	if e == nil {
+		return "", ErrNotConfigured
+	}
+	return e.transit.Decrypt(ctx, e.key, s)
+}
+
+// serializer encrypts on write and, in DecryptAlways mode, decrypts on load
+type serializer struct{}
+
+func (serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("fieldcrypt: unsupported column value %T for %s", dbValue, field.Name)
+	}
+
+	if e := current.Load(); e != nil && e.mode == DecryptAlways && IsCiphertext(value) {
+		plaintext, err := e.transit.Decrypt(ctx, e.key, value)
+		if err != nil {
+			return fmt.Errorf("fieldcrypt: decrypt %s: %w", field.Name, err)
+		}
+		value = plaintext
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("fieldcrypt: %s must be a string", field.Name)
+	}
+
+	// A client could send "vault:v1:..." itself, to have a leaked
+	// ciphertext decrypted or to break reads of the row, so ciphertexts
+	// are only passed through for rows the server loaded
+	e := current.Load()
+	if e == nil || value == "" || (IsCiphertext(value) && keepsCiphertexts(ctx)) {
+		return value, nil
+	}
+	ciphertext, err := e.transit.Encrypt(ctx, e.key, value)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: encrypt %s: %w", field.Name, err)
+	}
+	return ciphertext, nil
+}
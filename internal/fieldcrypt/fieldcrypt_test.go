@@ -0,0 +1,248 @@
+package fieldcrypt
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"casdoor-casbin-openbao/internal/openbao"
+	"casdoor-casbin-openbao/internal/openbao/openbaotest"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// note is a model with one encrypted field
+type note struct {
+	ID   string `gorm:"primaryKey"`
+	Body string `gorm:"serializer:transit"`
+}
+
+// setup returns a database with a notes table and a transit engine on a
+// fake OpenBao. Encryption stays off until configure is called.
+func setup(t *testing.T) (*gorm.DB, *openbao.Transit) {
+	t.Helper()
+	fake := openbaotest.NewFake("root")
+	t.Cleanup(fake.Close)
+	fake.MountTransit("transit")
+	client, err := openbao.NewClient(openbao.Config{Address: fake.URL(), Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transit := client.Transit("transit")
+	if err := transit.CreateKey(context.Background(), "fields"); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { current.Store(nil) })
+	return db, transit
+}
+
+func configure(t *testing.T, transit *openbao.Transit, mode Mode) {
+	t.Helper()
+	if err := Configure(transit, "fields", mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stored returns the column value of a note as it is in the database
+func stored(t *testing.T, db *gorm.DB, id string) string {
+	t.Helper()
+	var value string
+	if err := db.Table("notes").Select("body").Where("id = ?", id).Scan(&value).Error; err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func load(t *testing.T, db *gorm.DB, id string) string {
+	t.Helper()
+	var n note
+	if err := db.First(&n, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n.Body
+}
+
+func TestRoundTrip(t *testing.T) {
+	db, transit := setup(t)
+	configure(t, transit, DecryptAlways)
+
+	if err := db.Create(&note{ID: "n1", Body: "221B Baker Street"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if raw := stored(t, db, "n1"); !strings.HasPrefix(raw, "vault:v1:") {
+		t.Errorf("stored value = %q, want a v1 ciphertext", raw)
+	}
+	if body := load(t, db, "n1"); body != "221B Baker Street" {
+		t.Errorf("loaded value = %q, want the plain text", body)
+	}
+
+	// Empty values stay empty rather than encrypting nothing
+	if err := db.Create(&note{ID: "n2"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if raw := stored(t, db, "n2"); raw != "" {
+		t.Errorf("stored empty value = %q, want empty", raw)
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	db, _ := setup(t)
+
+	if err := db.Create(&note{ID: "n1", Body: "plain"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if raw := stored(t, db, "n1"); raw != "plain" {
+		t.Errorf("stored value = %q, want plain text while encryption is off", raw)
+	}
+}
+
+func TestDecryptModes(t *testing.T) {
+	db, transit := setup(t)
+	ctx := context.Background()
+	configure(t, transit, DecryptAuthorized)
+
+	if err := db.Create(&note{ID: "n1", Body: "secret"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// authorized: loaded models keep the ciphertext
+	if DecryptOnLoad() {
+		t.Error("DecryptOnLoad() = true in authorized mode")
+	}
+	body := load(t, db, "n1")
+	if !IsCiphertext(body) {
+		t.Fatalf("loaded value = %q, want the ciphertext in authorized mode", body)
+	}
+	plaintext, err := Decrypt(ctx, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "secret" {
+		t.Errorf("Decrypt = %q, want %q", plaintext, "secret")
+	}
+
+	// always: loaded models hold plain text
+	configure(t, transit, DecryptAlways)
+	if !DecryptOnLoad() {
+		t.Error("DecryptOnLoad() = false in always mode")
+	}
+	if body := load(t, db, "n1"); body != "secret" {
+		t.Errorf("loaded value = %q, want the plain text in always mode", body)
+	}
+
+	if err := Configure(transit, "fields", "sometimes"); err == nil {
+		t.Error("Configure accepted an unknown mode")
+	}
+}
+
+func TestClientCiphertextIsEncrypted(t *testing.T) {
+	db, transit := setup(t)
+	configure(t, transit, DecryptAlways)
+
+	if err := db.Create(&note{ID: "victim", Body: "victim's address"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	leaked := stored(t, db, "victim")
+
+	// A client submits a leaked ciphertext and a malformed one as their own
+	// values; both are stored encrypted like any other text
+	for id, body := range map[string]string{"replay": leaked, "garbage": "vault:v1:not-a-ciphertext"} {
+		if err := db.Create(&note{ID: id, Body: body}).Error; err != nil {
+			t.Fatal(err)
+		}
+		raw := stored(t, db, id)
+		if raw == body || !IsCiphertext(raw) {
+			t.Errorf("%s: stored value = %q, want a new ciphertext of the submitted text", id, raw)
+		}
+		if got := load(t, db, id); got != body {
+			t.Errorf("%s: loaded value = %q, want the submitted text %q", id, got, body)
+		}
+	}
+
+	// Listing every row still works
+	var notes []note
+	if err := db.Find(&notes).Error; err != nil {
+		t.Fatalf("listing notes: %v", err)
+	}
+}
+
+func TestKeepCiphertexts(t *testing.T) {
+	db, transit := setup(t)
+	configure(t, transit, DecryptAuthorized)
+
+	if err := db.Create(&note{ID: "n1", Body: "secret"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	before := stored(t, db, "n1")
+
+	// A repository writing back a row it loaded keeps its ciphertext
+	var n note
+	if err := db.First(&n, "id = ?", "n1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.WithContext(KeepCiphertexts(context.Background())).Save(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if after := stored(t, db, "n1"); after != before {
+		t.Errorf("stored value changed from %q to %q, want it kept", before, after)
+	}
+}
+
+func TestRewrapAfterRotation(t *testing.T) {
+	db, transit := setup(t)
+	ctx := context.Background()
+
+	// Written before encryption was enabled
+	if err := db.Create(&note{ID: "n1", Body: "old plain text"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	configure(t, transit, DecryptAlways)
+	if err := db.Create(&note{ID: "n2", Body: "first version"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	version, results, err := Rotate(ctx, db, &note{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Errorf("Rotate version = %d, want 2", version)
+	}
+	if len(results) != 1 || results[0].Rewrapped != 1 || results[0].Encrypted != 1 {
+		t.Errorf("Rotate results = %+v, want 1 rewrapped and 1 encrypted", results)
+	}
+	for id, want := range map[string]string{"n1": "old plain text", "n2": "first version"} {
+		if raw := stored(t, db, id); !strings.HasPrefix(raw, "vault:v2:") {
+			t.Errorf("%s: stored value = %q, want a v2 ciphertext", id, raw)
+		}
+		if got := load(t, db, id); got != want {
+			t.Errorf("%s: loaded value = %q, want %q", id, got, want)
+		}
+	}
+
+	// Nothing left to do on a second run
+	results, err = Rewrap(ctx, db, &note{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Rewrapped != 0 || results[0].Encrypted != 0 {
+		t.Errorf("second Rewrap results = %+v, want nothing rewrapped", results)
+	}
+
+	status, err := KeyStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LatestVersion != 2 {
+		t.Errorf("KeyStatus latest version = %d, want 2", status.LatestVersion)
+	}
+}
@@ -0,0 +1,133 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// rewrapBatch is the number of rows read per query
+const rewrapBatch = 200
+
+// RewrapResult counts the values updated in one column
+type RewrapResult struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	Rewrapped int    `json:"rewrapped"` // Older key versions moved to the latest
+	Encrypted int    `json:"encrypted"` // Plain text written before encryption was enabled
+}
+
+// Rotate adds a key version and rewraps the encrypted columns of models
+func Rotate(ctx context.Context, db *gorm.DB, models ...interface{}) (int, []RewrapResult, error) {
+	e := current.Load()
+	if e == nil {
+		return 0, nil, ErrNotConfigured
+	}
+	if err := e.transit.RotateKey(ctx, e.key); err != nil {
+		return 0, nil, fmt.Errorf("failed to rotate key: %w", err)
+	}
+	results, err := Rewrap(ctx, db, models...)
+	if err != nil {
+		return 0, results, err
+	}
+	key, err := e.transit.Key(ctx, e.key)
+	if err != nil {
+		return 0, results, err
+	}
+	return key.LatestVersion, results, nil
+}
+
+// Rewrap brings every encrypted column of models to the latest key
+// version. Rows are updated one at a time and only if unchanged since they
+// were read, so it is safe to run while the server takes writes.
+func Rewrap(ctx context.Context, db *gorm.DB, models ...interface{}) ([]RewrapResult, error) {
+	e := current.Load()
+	if e == nil {
+		return nil, ErrNotConfigured
+	}
+	key, err := e.transit.Key(ctx, e.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	latest := fmt.Sprintf("vault:v%d:%%", key.LatestVersion)
+
+	var results []RewrapResult
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return results, err
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if pk == nil {
+			return results, fmt.Errorf("%s has no primary key", stmt.Schema.Table)
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.TagSettings["SERIALIZER"] != SerializerName {
+				continue
+			}
+			result, err := rewrapColumn(ctx, e, db, stmt.Schema.Table, pk.DBName, field.DBName, latest)
+			results = append(results, result)
+			if err != nil {
+				return results, err
+			}
+		}
+	}
+	return results, nil
+}
+
+func rewrapColumn(ctx context.Context, e *engine, db *gorm.DB, table, pk, column, latest string) (RewrapResult, error) {
+	result := RewrapResult{Table: table, Column: column}
+	db = db.WithContext(ctx)
+	q := db.Statement.Quote
+
+	last := ""
+	for {
+		var rows []struct {
+			ID    string
+			Value string
+		}
+		err := db.Table(table).
+			Select(fmt.Sprintf("%s AS id, %s AS value", q(pk), q(column))).
+			Where(fmt.Sprintf("%s > ? AND %s <> '' AND %s NOT LIKE ?", q(pk), q(column), q(column)), last, latest).
+			Order(q(pk)).
+			Limit(rewrapBatch).
+			Scan(&rows).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+
+		for _, row := range rows {
+			var updated string
+			if IsCiphertext(row.Value) {
+				updated, err = e.transit.Rewrap(ctx, e.key, row.Value)
+			} else {
+				updated, err = e.transit.Encrypt(ctx, e.key, row.Value)
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed to rewrap %s %s: %w", table, row.ID, err)
+			}
+
+			res := db.Table(table).
+				Where(fmt.Sprintf("%s = ? AND %s = ?", q(pk), q(column)), row.ID, row.Value).
+				UpdateColumn(column, updated)
+			if res.Error != nil {
+				return result, fmt.Errorf("failed to update %s %s: %w", table, row.ID, res.Error)
+			}
+			if res.RowsAffected == 0 {
+				continue // Changed meanwhile; the new value is already current
+			}
+			if IsCiphertext(row.Value) {
+				result.Rewrapped++
+			} else {
+				result.Encrypted++
+			}
+		}
+
+		if len(rows) < rewrapBatch {
+			return result, nil
+		}
+		last = rows[len(rows)-1].ID
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type AccountHandler struct {
+	ledger *ledger.Service
+}
+
+func NewAccountHandler(ledger *ledger.Service) *AccountHandler {
+	return &AccountHandler{ledger: ledger}
+}
+
+// GetBalance returns the balance and ledger entries of an account
+// GET /api/accounts/:id/balance
+func (h *AccountHandler) GetBalance(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	account, entries, err := h.ledger.Balance(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "account not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load account")
+	}
+
+	// Owner, a delegate of the owner or admin; sharing single records does
+	// not expose the account
+	if !user.IsAdmin && account.Owner != user.Name && !casbin.CanActFor(user.Name, account.Owner) {
+		return echo.NewHTTPError(http.StatusForbidden, "can only access your own accounts")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"account_id":  account.ID,
+		"owner":       account.Owner,
+		"currency":    account.Currency,
+		"balance":     account.Balance,
+		"entries":     entries,
+		"accessed_by": user.Name,
+	})
+}
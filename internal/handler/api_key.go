@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHandler lets users manage their personal API keys
+type APIKeyHandler struct {
+	keys repository.APIKeyRepository
+}
+
+func NewAPIKeyHandler(keys repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// apiKeyRequest is the body of a create request. ExpiresIn is a duration
+// such as "720h"; without it and ExpiresAt the key lives API_KEY_DEFAULT_TTL.
+type apiKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresIn string     `json:"expires_in"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKey creates an API key for the caller; the key is only shown in
+// this response
+// POST /api/auth/api-keys
+// Body: {"name": "nightly export", "scopes": ["orders:export", "transactions:export"], "expires_in": "720h"}
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+	// Keys belong to people: a key must not mint keys that outlive it, and
+	// services would bypass their account being disabled
+	if user.APIKeyID != "" {
+		return echo.NewHTTPError(http.StatusForbidden, "API keys cannot create API keys")
+	}
+	if user.Owner == "service" {
+		return echo.NewHTTPError(http.StatusForbidden, "services cannot create API keys")
+	}
+
+	var req apiKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one scope is required (object:action, or * for all of your permissions)")
+	}
+	for i, scope := range req.Scopes {
+		req.Scopes[i] = strings.TrimSpace(scope)
+		if _, _, err := auth.ParseScope(req.Scopes[i]); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	now := time.Now()
+	expiresAt, err := apiKeyExpiry(req, now)
+	if err != nil {
+		return err
+	}
+
+	secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to generate API key")
+	}
+	key := model.APIKey{
+		ID:          model.NewID("key"),
+		Name:        req.Name,
+		Prefix:      secret[:len(model.APIKeyPrefix)+8],
+		Hash:        model.HashAPIKey(secret),
+		Scopes:      req.Scopes,
+		UserName:    user.Name,
+		UserID:      user.GetUserID(),
+		Owner:       user.Owner,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}
+	if err := h.keys.Create(c.Request().Context(), &key); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save API key")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "API key created; store it now, it is not shown again",
+		"key":     secret,
+		"api_key": key,
+	})
+}
+
+// apiKeyExpiry returns the requested expiry, or the default, within API_KEY_MAX_TTL
+func apiKeyExpiry(req apiKeyRequest, now time.Time) (time.Time, error) {
+	cfg := config.GetConfig().APIKeys
+	expiresAt := now.Add(cfg.DefaultTTL)
+	switch {
+	case req.ExpiresIn != "" && req.ExpiresAt != nil:
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "send expires_in or expires_at, not both")
+	case req.ExpiresIn != "":
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "expires_in must be a positive duration such as 720h")
+		}
+		expiresAt = now.Add(ttl)
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "expires_at must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+	if cfg.MaxTTL > 0 && expiresAt.After(now.Add(cfg.MaxTTL)) {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "API keys may live at most "+cfg.MaxTTL.String())
+	}
+	return expiresAt, nil
+}
+
+// GetAPIKeys lists the caller's API keys (without the keys themselves)
+// GET /api/auth/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	keys, err := h.keys.ListByUser(c.Request().Context(), user.Name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load API keys")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"api_keys": keys,
+		"count":    len(keys),
+		"message":  "API keys retrieved",
+	})
+}
+
+// RevokeAPIKey deletes one of the caller's API keys
+// DELETE /api/auth/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	if err := h.keys.Delete(c.Request().Context(), user.Name, c.Param("id")); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "API key not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke API key")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "API key revoked",
+	})
+}
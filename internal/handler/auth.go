@@ -4,24 +4,35 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/baotoken"
 	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/loginlimit"
 
 	"github.com/labstack/echo/v4"
 )
 
 type AuthHandler struct {
-	config *config.Config
+	config  *config.Config
+	tokens  *baotoken.Issuer
+	limiter *loginlimit.Limiter
 }
 
-func NewAuthHandler() *AuthHandler {
+// NewAuthHandler creates the auth handler. tokens may be nil when user
+// OpenBao tokens are not issued; limiter throttles direct logins.
+func NewAuthHandler(tokens *baotoken.Issuer, limiter *loginlimit.Limiter) *AuthHandler {
 	return &AuthHandler{
-		config: config.GetConfig(),
+		config:  config.GetConfig(),
+		tokens:  tokens,
+		limiter: limiter,
 	}
 }
 
@@ -44,10 +55,37 @@ func (h *AuthHandler) DirectLogin(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "username and password are required")
 	}
 
+	// Refuse attempts during backoff or lockout before asking Casdoor, and
+	// count this one before the password is checked
+	ctx := c.Request().Context()
+	ip := c.RealIP()
+	attempt, err := h.limiter.Begin(ctx, ip, req.Username)
+	if err != nil {
+		var blocked *loginlimit.BlockedError
+		if errors.As(err, &blocked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(blocked.Seconds()))
+			return echo.NewHTTPError(http.StatusTooManyRequests, blocked.Error())
+		}
+		log.Printf("Warning: login limiter unavailable: %v", err)
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "login temporarily unavailable")
+	}
+
 	// Login with Casdoor
 	token, err := auth.DirectLogin(req.Username, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		attempt.Failure(ctx)
+		// Casdoor's reason would tell which usernames exist
+		return echo.NewHTTPError(http.StatusUnauthorized, "login failed: "+auth.ErrInvalidCredentials.Error())
+	}
 	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, "login failed: "+err.Error())
+		if err := attempt.Cancel(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		log.Printf("Warning: direct login failed: %v", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "login failed: Casdoor is unavailable")
+	}
+	if err := attempt.Success(ctx); err != nil {
+		log.Printf("Warning: failed to reset login failures: %v", err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -145,7 +183,8 @@ type TokenResponse struct {
 
 // exchangeCodeForToken exchanges authorization code for access token
 func (h *AuthHandler) exchangeCodeForToken(code string) (*TokenResponse, error) {
-	cfg := h.config
+	// Read the config per call: the client secret may be refreshed from OpenBao
+	cfg := config.GetConfig()
 
 	tokenURL := fmt.Sprintf("%s/api/login/oauth/access_token", cfg.Casdoor.Endpoint)
 
@@ -183,11 +222,24 @@ func (h *AuthHandler) exchangeCodeForToken(code string) (*TokenResponse, error)
 	return &tokenResp, nil
 }
 
-// Logout handles logout
+// Logout handles logout and revokes the OpenBao tokens issued for the
+// session of the Bearer token, if one is sent
 // POST /api/auth/logout
 func (h *AuthHandler) Logout(c echo.Context) error {
+	revoked := 0
+	if token, ok := auth.BearerToken(c); ok && h.tokens != nil {
+		if claims, err := auth.VerifyToken(token); err == nil {
+			n, err := h.tokens.Revoke(c.Request().Context(), baotoken.Session(claims, token))
+			if err != nil {
+				log.Printf("Warning: failed to revoke OpenBao tokens of %s: %v", claims.Name, err)
+			}
+			revoked = n
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Logout successful",
+		"message":                "Logout successful",
+		"openbao_tokens_revoked": revoked,
 	})
 }
 
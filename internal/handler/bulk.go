@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/bulk"
+	"casdoor-casbin-openbao/internal/repository"
+	"casdoor-casbin-openbao/internal/tabular"
+	"github.com/labstack/echo/v4"
+)
+
+// maxImportSize limits the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+type BulkHandler struct {
+	orders       repository.OrderRepository
+	transactions repository.TransactionRepository
+	importer     *bulk.Importer
+}
+
+func NewBulkHandler(orders repository.OrderRepository, transactions repository.TransactionRepository, importer *bulk.Importer) *BulkHandler {
+	return &BulkHandler{orders: orders, transactions: transactions, importer: importer}
+}
+
+// ExportOrders streams orders as CSV or XLSX
+// GET /api/orders/export?format=csv|xlsx plus the list filters and sort
+func (h *BulkHandler) ExportOrders(c echo.Context) error {
+	return export(c, "orders", bulk.OrderColumns, h.orders.List, bulk.OrderRow)
+}
+
+// ExportTransactions streams transactions as CSV or XLSX
+// GET /api/transactions/export?format=csv|xlsx plus the list filters and sort
+func (h *BulkHandler) ExportTransactions(c echo.Context) error {
+	return export(c, "transactions", bulk.TransactionColumns, h.transactions.List, bulk.TransactionRow)
+}
+
+// export streams every record matching the list filters and the caller's
+// data scope, one page at a time, with unreadable fields blanked. The
+// first page is fetched before the response starts so invalid filters
+// still get a 400; later errors can only abort the stream.
+func export[T any](c echo.Context, resource string, columns []string,
+	list func(ctx context.Context, opts repository.ListOptions) (*repository.Page[T], error),
+	row func(T) []string) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = tabular.FormatCSV
+	}
+	if format != tabular.FormatCSV && format != tabular.FormatXLSX {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be csv or xlsx")
+	}
+
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return err
+	}
+	if opts.Filter.Scope, err = dataScope(user, resource); err != nil {
+		return err
+	}
+	// Exports cover every matching record; limit and cursor only page internally
+	opts.Limit = repository.MaxListLimit
+	opts.Cursor = ""
+
+	ctx := c.Request().Context()
+	fields, err := fieldShaper(ctx, user, resource)
+	if err != nil {
+		return err
+	}
+
+	page, err := list(ctx, opts)
+	if err != nil {
+		return listError(err, resource)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, tabular.ContentType(format))
+	filename := resource + "-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	w, err := tabular.NewWriter(res, format)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+
+	for {
+		for _, item := range page.Items {
+			cells, err := fields.Row(columns, row(item))
+			if err != nil {
+				log.Printf("Warning: %s export by %s aborted: %v", resource, user.Name, err)
+				return nil
+			}
+			if err := w.Write(cells); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		res.Flush()
+
+		opts.Cursor = page.NextCursor
+		if page, err = list(ctx, opts); err != nil {
+			// Headers are sent; the truncated file is the only signal left
+			log.Printf("Warning: %s export by %s aborted: %v", resource, user.Name, err)
+			return nil
+		}
+	}
+	return w.Close()
+}
+
+// ImportOrders imports orders from a CSV or XLSX file
+// POST /api/orders/import
+func (h *BulkHandler) ImportOrders(c echo.Context) error {
+	return h.runImport(c, "orders", h.importer.ImportOrders)
+}
+
+// ImportTransactions imports transactions from a CSV or XLSX file
+// POST /api/transactions/import
+func (h *BulkHandler) ImportTransactions(c echo.Context) error {
+	return h.runImport(c, "transactions", h.importer.ImportTransactions)
+}
+
+// runImport reads the upload and applies it all-or-nothing. The file is
+// either the raw request body (Content-Type text/csv or the XLSX type) or
+// a multipart "file" field; ?format=csv|xlsx overrides detection.
+func (h *BulkHandler) runImport(c echo.Context, resource string, run func(ctx context.Context, rows [][]string, importedBy *auth.CasdoorClaims) (*bulk.Result, error)) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	data, format, err := readImportFile(c)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tabular.ReadAll(data, format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read "+format+" file: "+err.Error())
+	}
+
+	result, err := run(c.Request().Context(), rows, user)
+	if err != nil {
+		if errors.Is(err, bulk.ErrInvalidRows) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, map[string]interface{}{
+				"message":  "nothing was imported: fix the listed rows and retry",
+				"errors":   result.Errors,
+				"imported": 0,
+			})
+		}
+		log.Printf("Warning: %s import by %s failed: %v", resource, user.Name, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import "+resource)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"imported":         result.Imported,
+		"pending_approval": result.PendingApproval,
+		"message":          "Import of " + resource + " completed",
+		"imported_by":      user.Name,
+	})
+}
+
+// readImportFile returns the uploaded file and its format
+func readImportFile(c echo.Context) ([]byte, string, error) {
+	req := c.Request()
+	format := c.QueryParam("format")
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+
+	var body io.Reader = req.Body
+	if mediaType == echo.MIMEMultipartForm {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", echo.NewHTTPError(http.StatusBadRequest, "missing file field")
+		}
+		if fh.Size > maxImportSize {
+			return nil, "", echo.NewHTTPError(http.StatusRequestEntityTooLarge, "import file is too large")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", echo.NewHTTPError(http.StatusBadRequest, "failed to read file")
+		}
+		defer f.Close()
+		body = f
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fh.Filename)), ".")
+		}
+	} else if format == "" {
+		format = tabular.FormatCSV
+		if mediaType == tabular.ContentType(tabular.FormatXLSX) {
+			format = tabular.FormatXLSX
+		}
+	}
+	if format != tabular.FormatCSV && format != tabular.FormatXLSX {
+		return nil, "", echo.NewHTTPError(http.StatusBadRequest, "format must be csv or xlsx")
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(body, maxImportSize+1))
+	if err != nil {
+		return nil, "", echo.NewHTTPError(http.StatusBadRequest, "failed to read file")
+	}
+	if n > maxImportSize {
+		return nil, "", echo.NewHTTPError(http.StatusRequestEntityTooLarge, "import file is too large")
+	}
+	return buf.Bytes(), format, nil
+}
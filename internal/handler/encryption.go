@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"casdoor-casbin-openbao/internal/fieldcrypt"
+	"casdoor-casbin-openbao/internal/model"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// encryptedModels are the models with transit-encrypted fields
+var encryptedModels = []interface{}{&model.Order{}, &model.Transaction{}}
+
+// EncryptionHandler manages the field encryption key
+type EncryptionHandler struct {
+	db *gorm.DB
+}
+
+func NewEncryptionHandler(db *gorm.DB) *EncryptionHandler {
+	return &EncryptionHandler{db: db}
+}
+
+// GetEncryptionStatus returns the encryption key versions and decrypt mode
+// GET /api/admin/encryption
+func (h *EncryptionHandler) GetEncryptionStatus(c echo.Context) error {
+	status, err := fieldcrypt.KeyStatus(c.Request().Context())
+	if err != nil {
+		return encryptionError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "Encryption key status",
+		"encryption": status,
+	})
+}
+
+// RotateEncryptionKey adds a key version and rewraps every encrypted
+// field with it
+// POST /api/admin/encryption/rotate
+func (h *EncryptionHandler) RotateEncryptionKey(c echo.Context) error {
+	version, results, err := fieldcrypt.Rotate(c.Request().Context(), h.db, encryptedModels...)
+	if err != nil {
+		log.Printf("Warning: encryption key rotation: %v", err)
+		return encryptionError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Encryption key rotated and data rewrapped",
+		"version": version,
+		"columns": results,
+	})
+}
+
+// RewrapEncryptedFields moves encrypted fields to the latest key version
+// and encrypts values stored before encryption was enabled
+// POST /api/admin/encryption/rewrap
+func (h *EncryptionHandler) RewrapEncryptedFields(c echo.Context) error {
+	results, err := fieldcrypt.Rewrap(c.Request().Context(), h.db, encryptedModels...)
+	if err != nil {
+		log.Printf("Warning: encrypted field rewrap: %v", err)
+		return encryptionError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Encrypted fields rewrapped",
+		"columns": results,
+	})
+}
+
+func encryptionError(err error) error {
+	if errors.Is(err, fieldcrypt.ErrNotConfigured) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "field encryption is not configured")
+	}
+	return echo.NewHTTPError(http.StatusBadGateway, "field encryption request failed")
+}
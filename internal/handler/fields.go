@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/fieldauth"
+	"github.com/labstack/echo/v4"
+)
+
+// fieldShaper loads the caller's field-level read permissions on resource
+func fieldShaper(ctx context.Context, user *auth.CasdoorClaims, resource string) (*fieldauth.Shaper, error) {
+	shaper, err := fieldauth.For(ctx, user, resource)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+	}
+	return shaper, nil
+}
+
+// shapeFields hides the fields of resource in v that user may not read
+func shapeFields(ctx context.Context, user *auth.CasdoorClaims, resource string, v interface{}) (interface{}, error) {
+	shaper, err := fieldShaper(ctx, user, resource)
+	if err != nil {
+		return nil, err
+	}
+	return applyShaper(shaper, v)
+}
+
+func applyShaper(shaper *fieldauth.Shaper, v interface{}) (interface{}, error) {
+	shaped, err := shaper.Apply(v)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to build response")
+	}
+	return shaped, nil
+}
+
+// checkFieldWrites rejects requests that set fields the caller may not write
+func checkFieldWrites(user *auth.CasdoorClaims, resource string, fields ...string) error {
+	err := fieldauth.CheckWrite(user, resource, fields...)
+	var forbidden *fieldauth.ForbiddenFieldsError
+	if errors.As(err, &forbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, map[string]interface{}{
+			"message": "not allowed to set these fields",
+			"fields":  forbidden.Fields,
+		})
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// parseListOptions reads the shared list grammar from the query string:
+// limit, cursor, sort (e.g. "-created_at", "amount") and the filters
+// status, type, user, currency, from, to, min_amount, max_amount.
+// Dates accept RFC3339 or YYYY-MM-DD; "to" is exclusive. Amounts are
+// decimal strings in "currency" (default USD).
+func parseListOptions(c echo.Context) (repository.ListOptions, error) {
+	opts := repository.ListOptions{
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+		Filter: repository.Filter{
+			UserID:   c.QueryParam("user"),
+			Status:   c.QueryParam("status"),
+			Type:     c.QueryParam("type"),
+			Currency: c.QueryParam("currency"),
+		},
+	}
+
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+
+	var err error
+	if opts.Filter.From, err = parseDateParam(c, "from"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.To, err = parseDateParam(c, "to"); err != nil {
+		return opts, err
+	}
+	if opts.Filter.MinAmount, err = parseAmountParam(c, "min_amount", opts.Filter.Currency); err != nil {
+		return opts, err
+	}
+	if opts.Filter.MaxAmount, err = parseAmountParam(c, "max_amount", opts.Filter.Currency); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+func parseDateParam(c echo.Context, name string) (*time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, echo.NewHTTPError(http.StatusBadRequest, name+" must be RFC3339 or YYYY-MM-DD")
+}
+
+func parseAmountParam(c echo.Context, name, currency string) (*money.Money, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	amount, err := money.Parse(v, currency)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, name+": "+err.Error())
+	}
+	return &amount, nil
+}
+
+// listError maps list errors to HTTP errors
+func listError(err error, resource string) error {
+	if errors.Is(err, repository.ErrInvalidListOptions) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to list "+resource)
+}
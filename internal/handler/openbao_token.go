@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/baotoken"
+	"github.com/labstack/echo/v4"
+)
+
+// OpenBaoTokenHandler exchanges the caller's Casdoor session for an
+// OpenBao token scoped to their Casbin roles
+type OpenBaoTokenHandler struct {
+	tokens *baotoken.Issuer
+}
+
+// NewOpenBaoTokenHandler serves tokens from tokens. A nil issuer means
+// OpenBao is not configured and every request returns 503.
+func NewOpenBaoTokenHandler(tokens *baotoken.Issuer) *OpenBaoTokenHandler {
+	return &OpenBaoTokenHandler{tokens: tokens}
+}
+
+// IssueToken returns a short-lived OpenBao token for the caller
+// POST /api/auth/openbao-token
+func (h *OpenBaoTokenHandler) IssueToken(c echo.Context) error {
+	user, jwt, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+
+	token, err := h.tokens.Issue(c.Request().Context(), user.Name, baotoken.Session(user, jwt), jwt)
+	if err != nil {
+		if errors.Is(err, baotoken.ErrNoPolicies) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		log.Printf("Warning: OpenBao token for %s failed: %v", user.Name, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to issue OpenBao token")
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":    "OpenBao token issued",
+		"token":      token.Token,
+		"accessor":   token.Accessor,
+		"policies":   token.Policies,
+		"ttl":        token.TTL,
+		"expires_at": token.ExpiresAt,
+	})
+}
+
+// RevokeTokens revokes the OpenBao tokens issued for the caller's session
+// DELETE /api/auth/openbao-token
+func (h *OpenBaoTokenHandler) RevokeTokens(c echo.Context) error {
+	user, jwt, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.tokens.Revoke(c.Request().Context(), baotoken.Session(user, jwt))
+	if err != nil {
+		log.Printf("Warning: failed to revoke OpenBao tokens of %s: %v", user.Name, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to revoke OpenBao tokens")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "OpenBao tokens revoked",
+		"revoked": revoked,
+	})
+}
+
+func (h *OpenBaoTokenHandler) begin(c echo.Context) (*auth.CasdoorClaims, string, error) {
+	if h.tokens == nil {
+		return nil, "", echo.NewHTTPError(http.StatusServiceUnavailable, "OpenBao is not configured")
+	}
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return nil, "", echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+	jwt, ok := auth.BearerToken(c)
+	if !ok {
+		return nil, "", echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+	}
+	return user, jwt, nil
+}
@@ -1,103 +1,105 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/checkout"
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
 	"github.com/labstack/echo/v4"
 )
 
-type OrderHandler struct{}
-
-func NewOrderHandler() *OrderHandler {
-	return &OrderHandler{}
-}
-
-type Order struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	ProductName string    `json:"product_name"`
-	Quantity    int       `json:"quantity"`
-	Price       float64   `json:"price"`
-	Total       float64   `json:"total"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   string    `json:"created_by"`
+type OrderHandler struct {
+	orders   repository.OrderRepository
+	checkout *checkout.Service
 }
 
-// Mock data
-var orders = []Order{
-	{
-		ID:          "ord_001",
-		UserID:      "admin",
-		ProductName: "Laptop Pro",
-		Quantity:    1,
-		Price:       1299.99,
-		Total:       1299.99,
-		Status:      "delivered",
-		CreatedAt:   time.Now().Add(-48 * time.Hour),
-		CreatedBy:   "admin",
-	},
-	{
-		ID:          "ord_002",
-		UserID:      "hihi",
-		ProductName: "Wireless Mouse",
-		Quantity:    2,
-		Price:       29.99,
-		Total:       59.98,
-		Status:      "processing",
-		CreatedAt:   time.Now().Add(-6 * time.Hour),
-		CreatedBy:   "hihi",
-	},
-	{
-		ID:          "ord_003",
-		UserID:      "testuser",
-		ProductName: "USB Cable",
-		Quantity:    3,
-		Price:       9.99,
-		Total:       29.97,
-		Status:      "shipped",
-		CreatedAt:   time.Now().Add(-12 * time.Hour),
-		CreatedBy:   "testuser",
-	},
+func NewOrderHandler(orders repository.OrderRepository, checkout *checkout.Service) *OrderHandler {
+	return &OrderHandler{orders: orders, checkout: checkout}
 }
 
-// GetOrders returns all orders (admin only)
+// GetOrders returns the orders the caller may read. The caller's Casbin
+// data-scope policies (("orders", "scope:...")) become part of the query,
+// so admins see everything and other roles see only their rows.
 func (h *OrderHandler) GetOrders(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return err
+	}
+	if opts.Filter.Scope, err = dataScope(user, "orders"); err != nil {
+		return err
+	}
+
+	page, err := h.orders.List(c.Request().Context(), opts)
+	if err != nil {
+		return listError(err, "orders")
+	}
+	resolver := newAccessResolver(user, "orders")
+	for i := range page.Items {
+		page.Items[i].Access = resolver.access(page.Items[i].ID, page.Items[i].UserID, page.Items[i].Status)
+	}
+
+	orders, err := shapeFields(c.Request().Context(), user, "orders", page.Items)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"orders":     orders,
-		"total":      len(orders),
-		"message":    "All orders retrieved",
+		"orders":      orders,
+		"count":       len(page.Items),
+		"next_cursor": page.NextCursor,
+		"message":     "Orders retrieved",
 		"accessed_by": user.Name,
 	})
 }
 
-// GetMyOrders returns current user's orders only
+// GetMyOrders returns the current user's orders, orders of users who
+// delegated to them and orders shared with them. Each order carries an
+// "access" flag saying how it was reached; ?user= narrows to one owner.
 func (h *OrderHandler) GetMyOrders(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
-	var userOrders []Order
-	for _, order := range orders {
-		if order.UserID == user.Name {
-			userOrders = append(userOrders, order)
-		}
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return err
+	}
+	opts.Filter.Scope = ownScope(user.Name, "orders")
+
+	page, err := h.orders.List(c.Request().Context(), opts)
+	if err != nil {
+		return listError(err, "orders")
+	}
+	resolver := newAccessResolver(user, "orders")
+	for i := range page.Items {
+		page.Items[i].Access = resolver.access(page.Items[i].ID, page.Items[i].UserID, page.Items[i].Status)
+	}
+
+	orders, err := shapeFields(c.Request().Context(), user, "orders", page.Items)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"orders":  userOrders,
-		"total":   len(userOrders),
-		"message": "Your orders retrieved",
-		"user":    user.Name,
+		"orders":      orders,
+		"count":       len(page.Items),
+		"next_cursor": page.NextCursor,
+		"message":     "Your orders retrieved",
+		"user":        user.Name,
 	})
 }
 
@@ -108,92 +110,350 @@ func (h *OrderHandler) GetOrder(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
-	orderID := c.Param("id")
-	
-	for _, order := range orders {
-		if order.ID == orderID {
-			// Check ownership - user can only see their own orders unless admin
-			if !user.IsAdmin && order.UserID != user.Name {
-				return echo.NewHTTPError(http.StatusForbidden, "can only access your own orders")
-			}
-			
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"order":      order,
-				"message":    "Order retrieved",
-				"accessed_by": user.Name,
-			})
-		}
+	ctx := c.Request().Context()
+	order, err := h.orders.Get(ctx, c.Param("id"))
+	if err != nil {
+		return orderError(err)
+	}
+
+	// Owner, delegate, share grantee or admin. Linked payments and refunds
+	// are visible to everyone who can see the order.
+	order.Access = resourceAccess(user, "orders", order.ID, order.UserID, order.Status)
+	if order.Access == nil {
+		return echo.NewHTTPError(http.StatusForbidden, "no access to this order")
+	}
+
+	txns, err := h.checkout.Transactions(ctx, order.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load order transactions")
+	}
+	refundable, err := h.checkout.Refundable(ctx, order)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load order transactions")
 	}
 
-	return echo.NewHTTPError(http.StatusNotFound, "order not found")
+	orderFields, err := fieldShaper(c.Request().Context(), user, "orders")
+	if err != nil {
+		return err
+	}
+	shapedOrder, err := applyShaper(orderFields, order)
+	if err != nil {
+		return err
+	}
+	shapedTxns, err := shapeFields(c.Request().Context(), user, "transactions", txns)
+	if err != nil {
+		return err
+	}
+
+	response := map[string]interface{}{
+		"order":        shapedOrder,
+		"transactions": shapedTxns,
+		"message":      "Order retrieved",
+		"accessed_by":  user.Name,
+	}
+	// The refundable amount is derived from the total
+	if orderFields.CanRead("total") {
+		response["refundable"] = refundable
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
-// CreateOrder creates a new order
+// CreateOrder creates a new order for a catalog product, reserving stock
+// and charging the catalog price to the user's account. A delegate may
+// order for the user they act for with on_behalf_of.
+// POST /api/orders
+// Body: {"sku": "LAPTOP-PRO", "quantity": 1, "shipping_address": "...", "on_behalf_of": "manager"}
 func (h *OrderHandler) CreateOrder(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
+	// Name and price always come from the catalog, never from the client
 	var req struct {
-		ProductName string  `json:"product_name"`
-		Quantity    int     `json:"quantity"`
-		Price       float64 `json:"price"`
+		SKU             string `json:"sku"`
+		Quantity        int    `json:"quantity"`
+		ShippingAddress string `json:"shipping_address"`
+		OnBehalfOf      string `json:"on_behalf_of"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
 
-	newOrder := Order{
-		ID:          "ord_" + strconv.FormatInt(time.Now().Unix(), 10),
-		UserID:      user.Name,
-		ProductName: req.ProductName,
-		Quantity:    req.Quantity,
-		Price:       req.Price,
-		Total:       req.Price * float64(req.Quantity),
-		Status:      "pending",
-		CreatedAt:   time.Now(),
-		CreatedBy:   user.Name,
+	if req.SKU == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "sku is required")
+	}
+	if req.Quantity <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "quantity must be positive")
+	}
+	fields := []string{"quantity"}
+	if strings.TrimSpace(req.ShippingAddress) != "" {
+		fields = append(fields, "shipping_address")
+	}
+	if err := checkFieldWrites(user, "orders", fields...); err != nil {
+		return err
+	}
+
+	owner, err := actingFor(user, req.OnBehalfOf)
+	if err != nil {
+		return err
+	}
+
+	newOrder := model.Order{
+		ID:              model.NewID("ord"),
+		UserID:          owner,
+		SKU:             req.SKU,
+		Quantity:        req.Quantity,
+		ShippingAddress: strings.TrimSpace(req.ShippingAddress),
+		Status:          model.OrderStatusPending,
+		CreatedAt:       time.Now(),
+		CreatedBy:       user.Name,
 	}
 
-	orders = append(orders, newOrder)
+	if err := h.checkout.PlaceOrder(c.Request().Context(), &newOrder); err != nil {
+		return checkoutError(err)
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "orders", newOrder)
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"order":      newOrder,
+		"order":      shaped,
 		"message":    "Order created successfully",
 		"created_by": user.Name,
 	})
 }
 
-// UpdateOrderStatus updates order status (admin only)
+// UpdateOrderStatus moves an order to a new status
+// PUT /api/orders/:id/status
+// Body: {"status": "cancelled", "refund_amount": "5.00"}
+// Each transition is authorized separately as ("orders", "update-status:<status>").
+// Without that permission, the owner, a delegate or an editor may still
+// cancel a pending order, which always refunds the full payment.
+// Cancelling or returning a paid order refunds refund_amount, or the whole
+// remaining payment when it is omitted.
 func (h *OrderHandler) UpdateOrderStatus(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
-	orderID := c.Param("id")
-	
 	var req struct {
-		Status string `json:"status"`
+		Status       string `json:"status"`
+		RefundAmount string `json:"refund_amount"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
 
-	for i, order := range orders {
-		if order.ID == orderID {
-			orders[i].Status = req.Status
-			
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"order":      orders[i],
-				"message":    "Order status updated",
-				"updated_by": user.Name,
-			})
+	if !model.IsValidOrderStatus(req.Status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "unknown order status: "+req.Status)
+	}
+	fields := []string{"status"}
+	if req.RefundAmount != "" {
+		fields = append(fields, "refund_amount")
+	}
+	if err := checkFieldWrites(user, "orders", fields...); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	order, err := h.orders.Get(ctx, c.Param("id"))
+	if err != nil {
+		return orderError(err)
+	}
+
+	if !model.CanTransitionOrder(order.Status, req.Status) {
+		return echo.NewHTTPError(http.StatusConflict, map[string]interface{}{
+			"message": "cannot change order status from " + order.Status + " to " + req.Status,
+			"allowed": model.AllowedOrderTransitions(order.Status),
+		})
+	}
+
+	allowed, err := casbin.EnforceUser(user, "orders", "update-status:"+req.Status)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+	}
+	if !allowed && canCancelAsEditor(user, order, req.Status) && req.RefundAmount == "" {
+		allowed = true
+	}
+	if !allowed {
+		return echo.NewHTTPError(http.StatusForbidden, "not allowed to mark orders as "+req.Status)
+	}
+
+	refundAmount, err := parseRefundAmount(req.RefundAmount, order)
+	if err != nil {
+		return err
+	}
+
+	updated, refund, err := h.checkout.ChangeStatus(ctx, order.ID, order.Status, req.Status, user.Name, refundAmount)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "order status changed concurrently, retry")
 		}
+		return checkoutError(err)
+	}
+
+	shapedOrder, err := shapeFields(c.Request().Context(), user, "orders", updated)
+	if err != nil {
+		return err
 	}
 
-	return echo.NewHTTPError(http.StatusNotFound, "order not found")
-}
\ No newline at end of file
+	response := map[string]interface{}{
+		"order":       shapedOrder,
+		"from_status": order.Status,
+		"message":     "Order status updated",
+		"updated_by":  user.Name,
+	}
+	if refund != nil {
+		txnFields, err := fieldShaper(c.Request().Context(), user, "transactions")
+		if err != nil {
+			return err
+		}
+		if response["refund"], err = applyShaper(txnFields, refund); err != nil {
+			return err
+		}
+		response["message"] = "Order status updated and refund issued"
+		if txnFields.CanRead("amount") {
+			response["message"] = "Order status updated and refund of " + refund.Amount.String() + " " + refund.Amount.Currency + " issued"
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetOrderHistory returns the status history of an order
+// GET /api/orders/:id/history
+func (h *OrderHandler) GetOrderHistory(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	ctx := c.Request().Context()
+	order, err := h.orders.Get(ctx, c.Param("id"))
+	if err != nil {
+		return orderError(err)
+	}
+
+	if resourceAccess(user, "orders", order.ID, order.UserID, order.Status) == nil {
+		return echo.NewHTTPError(http.StatusForbidden, "no access to this order")
+	}
+
+	history, err := h.orders.ListStatusHistory(ctx, order.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load order history")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"order_id":    order.ID,
+		"status":      order.Status,
+		"history":     history,
+		"accessed_by": user.Name,
+	})
+}
+
+// RefundOrder issues a further refund for a cancelled or returned order
+// POST /api/orders/:id/refunds
+// Body: {"amount": "5.00"}; omit amount to refund everything not yet refunded
+func (h *OrderHandler) RefundOrder(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req struct {
+		Amount string `json:"amount"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	// Omitting amount refunds everything, which sets the amount too
+	if err := checkFieldWrites(user, "orders", "refund_amount"); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	order, err := h.orders.Get(ctx, c.Param("id"))
+	if err != nil {
+		return orderError(err)
+	}
+
+	amount, err := parseRefundAmount(req.Amount, order)
+	if err != nil {
+		return err
+	}
+
+	refund, err := h.checkout.Refund(ctx, order.ID, user.Name, amount)
+	if err != nil {
+		return checkoutError(err)
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "transactions", refund)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"refund":      shaped,
+		"message":     "Refund issued",
+		"refunded_by": user.Name,
+	})
+}
+
+// parseRefundAmount reads an optional refund amount in the order's currency
+func parseRefundAmount(s string, order *model.Order) (*money.Money, error) {
+	if s == "" {
+		return nil, nil
+	}
+	amount, err := money.Parse(s, order.Total.Currency)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid refund amount: "+err.Error())
+	}
+	return &amount, nil
+}
+
+// checkoutError maps checkout and ledger errors to HTTP errors
+func checkoutError(err error) error {
+	switch {
+	case errors.Is(err, checkout.ErrUnknownProduct):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrOutOfStock):
+		return echo.NewHTTPError(http.StatusConflict, "insufficient stock")
+	case errors.Is(err, checkout.ErrRefundExceedsPayment):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, checkout.ErrNotRefundable), errors.Is(err, checkout.ErrInvalidTransition):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, ledger.ErrInvalidTransaction):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrInsufficientFunds):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "insufficient funds")
+	case errors.Is(err, repository.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "order not found")
+	case errors.Is(err, repository.ErrConflict):
+		return echo.NewHTTPError(http.StatusConflict, "order changed concurrently, retry")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to process order")
+}
+
+// orderError maps repository errors to HTTP errors
+func orderError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "order not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to load order")
+}
+
+// canCancelAsEditor reports whether user may cancel order through
+// ownership, delegation or an editor share rather than a status permission
+func canCancelAsEditor(user *auth.CasdoorClaims, order *model.Order, status string) bool {
+	if status != model.OrderStatusCancelled || order.Status != model.OrderStatusPending {
+		return false
+	}
+	access := resourceAccess(user, "orders", order.ID, order.UserID, order.Status)
+	return access != nil && (access.Role == casbin.RoleOwner || access.Role == casbin.RoleEditor)
+}
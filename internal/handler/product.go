@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type ProductHandler struct {
+	products repository.ProductRepository
+}
+
+func NewProductHandler(products repository.ProductRepository) *ProductHandler {
+	return &ProductHandler{products: products}
+}
+
+// productRequest is the body of create and update requests. Omitted
+// fields keep their current value on update. StockAdjustment adds to or
+// takes from the stock on update without overwriting reservations.
+type productRequest struct {
+	SKU             string  `json:"sku"`
+	Name            *string `json:"name"`
+	Description     *string `json:"description"`
+	Price           *string `json:"price"`
+	Currency        string  `json:"currency"`
+	Stock           *int    `json:"stock"`
+	StockAdjustment *int    `json:"stock_adjustment"`
+	Active          *bool   `json:"active"`
+}
+
+// GetProducts returns the catalog
+// GET /api/products
+// Admins may add ?include_inactive=true to see inactive products
+func (h *ProductHandler) GetProducts(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	includeInactive := user.IsAdmin && c.QueryParam("include_inactive") == "true"
+	products, err := h.products.List(c.Request().Context(), includeInactive)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load products")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"products":    products,
+		"count":       len(products),
+		"message":     "Products retrieved",
+		"accessed_by": user.Name,
+	})
+}
+
+// GetProduct returns a product by ID
+// GET /api/products/:id
+func (h *ProductHandler) GetProduct(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	product, err := h.products.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return productError(err)
+	}
+	// Inactive products are hidden from everyone but admins
+	if !product.Active && !user.IsAdmin {
+		return echo.NewHTTPError(http.StatusNotFound, "product not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"product":     product,
+		"message":     "Product retrieved",
+		"accessed_by": user.Name,
+	})
+}
+
+// CreateProduct adds a product to the catalog (admin only)
+// POST /api/products
+// Body: {"sku": "LAPTOP-PRO", "name": "Laptop Pro", "price": "1299.99", "currency": "USD", "stock": 10}
+func (h *ProductHandler) CreateProduct(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req productRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	req.SKU = strings.TrimSpace(req.SKU)
+	if req.SKU == "" || req.Name == nil || *req.Name == "" || req.Price == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "sku, name and price are required")
+	}
+	if req.StockAdjustment != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "stock_adjustment is only allowed on update")
+	}
+	if err := checkFieldWrites(user, "products", req.fields()...); err != nil {
+		return err
+	}
+	update, err := productUpdate(req, "")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	product := model.Product{
+		ID:        model.NewID("prod"),
+		SKU:       req.SKU,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	update.Apply(&product)
+
+	if err := h.products.Create(c.Request().Context(), &product); err != nil {
+		return productError(err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"product":    product,
+		"message":    "Product created successfully",
+		"created_by": user.Name,
+	})
+}
+
+// UpdateProduct changes a product's details, price, stock or active flag (admin only)
+// PUT /api/products/:id
+// The SKU cannot be changed; orders refer to it. Setting price, stock,
+// stock_adjustment or active also needs ("products.<field>", "write").
+// Only the fields in the body are written.
+func (h *ProductHandler) UpdateProduct(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req productRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	ctx := c.Request().Context()
+	product, err := h.products.Get(ctx, c.Param("id"))
+	if err != nil {
+		return productError(err)
+	}
+	if req.SKU != "" && req.SKU != product.SKU {
+		return echo.NewHTTPError(http.StatusBadRequest, "sku cannot be changed")
+	}
+	if req.Name != nil && *req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name must not be empty")
+	}
+	if req.Stock != nil && req.StockAdjustment != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "set either stock or stock_adjustment, not both")
+	}
+	if err := checkFieldWrites(user, "products", req.fields()...); err != nil {
+		return err
+	}
+	update, err := productUpdate(req, product.Price.Currency)
+	if err != nil {
+		return err
+	}
+
+	product, err = h.products.Update(ctx, product.ID, update)
+	if err != nil {
+		return productError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"product":    product,
+		"message":    "Product updated",
+		"updated_by": user.Name,
+	})
+}
+
+// DeleteProduct removes a product from the catalog (admin only)
+// DELETE /api/products/:id
+// Existing orders keep their SKU and product name snapshot.
+func (h *ProductHandler) DeleteProduct(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	if err := h.products.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return productError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "Product deleted",
+		"deleted_by": user.Name,
+	})
+}
+
+// productUpdate validates the fields present in req. currency is the
+// product's current currency, used for a price sent without one.
+func productUpdate(req productRequest, currency string) (repository.ProductUpdate, error) {
+	update := repository.ProductUpdate{
+		Name:        req.Name,
+		Description: req.Description,
+		Stock:       req.Stock,
+		StockDelta:  req.StockAdjustment,
+		Active:      req.Active,
+	}
+	if req.Currency != "" && req.Price == nil {
+		return update, echo.NewHTTPError(http.StatusBadRequest, "changing the currency requires a price")
+	}
+	if req.Price != nil {
+		if req.Currency != "" {
+			currency = req.Currency
+		}
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+		price, err := money.Parse(*req.Price, currency)
+		if err != nil {
+			return update, echo.NewHTTPError(http.StatusBadRequest, "invalid price: "+err.Error())
+		}
+		if price.IsNegative() {
+			return update, echo.NewHTTPError(http.StatusBadRequest, "price must not be negative")
+		}
+		update.Price = &price
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		return update, echo.NewHTTPError(http.StatusBadRequest, "stock must not be negative")
+	}
+	return update, nil
+}
+
+// productError maps repository errors to HTTP errors
+func productError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "product not found")
+	case errors.Is(err, repository.ErrDuplicateSKU):
+		return echo.NewHTTPError(http.StatusConflict, "a product with this sku already exists")
+	case errors.Is(err, repository.ErrOutOfStock):
+		return echo.NewHTTPError(http.StatusConflict, "stock_adjustment would take the stock below zero")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to save product")
+}
+
+// fields returns the names of the fields set in the request
+func (r productRequest) fields() []string {
+	var fields []string
+	for name, set := range map[string]bool{
+		"sku":         r.SKU != "",
+		"name":        r.Name != nil,
+		"description": r.Description != nil,
+		"price":       r.Price != nil,
+		"stock":       r.Stock != nil || r.StockAdjustment != nil,
+		"active":      r.Active != nil,
+	} {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
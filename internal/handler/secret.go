@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/openbao"
+	"github.com/labstack/echo/v4"
+)
+
+// SecretHandler stores secrets in an OpenBao KV v2 engine. Every secret
+// lives under users/<name>/ or groups/<name>/ and each path is authorized
+// with Casbin (see casbin.SecretAllowed).
+type SecretHandler struct {
+	kv     *openbao.KV
+	prefix string
+}
+
+// NewSecretHandler serves the secrets under prefix in kv. A nil kv means
+// OpenBao is not configured and every request returns 503.
+func NewSecretHandler(kv *openbao.KV, prefix string) *SecretHandler {
+	return &SecretHandler{kv: kv, prefix: strings.Trim(prefix, "/")}
+}
+
+type secretRequest struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// secretSegment is one element of a secret path
+var secretSegment = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// GetSecretFolders lists the caller's own folder and their groups' folders
+// GET /api/secrets
+func (h *SecretHandler) GetSecretFolders(c echo.Context) error {
+	user, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+
+	folders := []string{"users/" + user.Name}
+	for _, group := range casbin.GroupsForUser(user.Name) {
+		folders = append(folders, "groups/"+group)
+	}
+
+	result := map[string][]string{}
+	for _, folder := range folders {
+		if validateSecretPath(folder) != nil {
+			continue
+		}
+		ok, err := casbin.SecretAllowed(user, folder, "list")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+		}
+		if !ok {
+			continue
+		}
+		keys, err := h.kv.List(c.Request().Context(), h.path(folder))
+		if err != nil && !errors.Is(err, openbao.ErrNotFound) {
+			return secretError(err)
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		result[folder+"/"] = keys
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Secret folders retrieved",
+		"user":    user.Name,
+		"folders": result,
+	})
+}
+
+// GetSecret reads a secret, or lists a folder when the path ends in "/"
+// GET /api/secrets/*
+func (h *SecretHandler) GetSecret(c echo.Context) error {
+	user, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+	raw := c.Param("*")
+	folder := strings.HasSuffix(raw, "/")
+	act := "read"
+	if folder {
+		act = "list"
+	}
+	path, err := h.authorize(user, raw, act)
+	if err != nil {
+		return err
+	}
+
+	if folder {
+		keys, err := h.kv.List(c.Request().Context(), h.path(path))
+		if err != nil {
+			return secretError(err)
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Secrets listed",
+			"path":    path + "/",
+			"keys":    keys,
+		})
+	}
+
+	secret, err := h.kv.Get(c.Request().Context(), h.path(path))
+	if err != nil {
+		return secretError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":      "Secret retrieved",
+		"path":         path,
+		"data":         secret.Data,
+		"version":      secret.Metadata.Version,
+		"created_time": secret.Metadata.CreatedTime,
+	})
+}
+
+// PutSecret writes a new version of a secret
+// PUT /api/secrets/*
+// Body: {"data": {"db_password": "..."}}
+func (h *SecretHandler) PutSecret(c echo.Context) error {
+	user, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+	path, err := h.authorize(user, c.Param("*"), "write")
+	if err != nil {
+		return err
+	}
+
+	var req secretRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Data) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "data is required")
+	}
+
+	meta, err := h.kv.Put(c.Request().Context(), h.path(path), req.Data)
+	if err != nil {
+		return secretError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Secret saved",
+		"path":    path,
+		"version": meta.Version,
+	})
+}
+
+// DeleteSecret deletes the latest version of a secret; ?permanent=true
+// destroys every version
+// DELETE /api/secrets/*
+func (h *SecretHandler) DeleteSecret(c echo.Context) error {
+	user, err := h.begin(c)
+	if err != nil {
+		return err
+	}
+	path, err := h.authorize(user, c.Param("*"), "delete")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if c.QueryParam("permanent") == "true" {
+		err = h.kv.Destroy(ctx, h.path(path))
+	} else {
+		err = h.kv.Delete(ctx, h.path(path))
+	}
+	if err != nil {
+		return secretError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Secret deleted",
+		"path":    path,
+	})
+}
+
+// begin returns the caller, or an error when OpenBao is not configured
+func (h *SecretHandler) begin(c echo.Context) (*auth.CasdoorClaims, error) {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+	if h.kv == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "OpenBao is not configured")
+	}
+	return user, nil
+}
+
+// authorize validates a secret path and checks the caller may perform act
+// on it. It returns the path without surrounding slashes.
+func (h *SecretHandler) authorize(user *auth.CasdoorClaims, raw, act string) (string, error) {
+	path := strings.Trim(raw, "/")
+	if err := validateSecretPath(path); err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if act != "list" && strings.Count(path, "/") < 2 {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "secret path must name a key below users/<name>/ or groups/<name>/")
+	}
+	ok, err := casbin.SecretAllowed(user, path, act)
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+	}
+	if !ok {
+		return "", echo.NewHTTPError(http.StatusForbidden, "no "+act+" access to secret path "+path)
+	}
+	return path, nil
+}
+
+// path returns the OpenBao path of a secret path
+func (h *SecretHandler) path(path string) string {
+	if h.prefix == "" {
+		return path
+	}
+	return h.prefix + "/" + path
+}
+
+// validateSecretPath requires users/<name>/... or groups/<name>/...
+func validateSecretPath(path string) error {
+	segments := strings.Split(path, "/")
+	if len(segments) < 2 || (segments[0] != "users" && segments[0] != "groups") {
+		return errors.New("secret path must start with users/<name> or groups/<name>")
+	}
+	for _, s := range segments {
+		if s == "." || s == ".." || !secretSegment.MatchString(s) {
+			return errors.New("invalid secret path segment: " + s)
+		}
+	}
+	return nil
+}
+
+func secretError(err error) error {
+	switch {
+	case errors.Is(err, openbao.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "secret not found")
+	case errors.Is(err, openbao.ErrPermissionDenied):
+		return echo.NewHTTPError(http.StatusBadGateway, "OpenBao denied the request")
+	default:
+		return echo.NewHTTPError(http.StatusBadGateway, "OpenBao request failed")
+	}
+}
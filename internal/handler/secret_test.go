@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/openbao"
+	"casdoor-casbin-openbao/internal/openbao/openbaotest"
+	casbinv2 "github.com/casbin/casbin/v2"
+	"github.com/labstack/echo/v4"
+)
+
+// newSecretHandler serves secrets from a fake OpenBao, with the default
+// secret policies: alice is a user in group finance, root is an admin
+func newSecretHandler(t *testing.T) (*SecretHandler, *openbao.KV) {
+	t.Helper()
+	fake := openbaotest.NewFake("root")
+	t.Cleanup(fake.Close)
+	client, err := openbao.NewClient(openbao.Config{Address: fake.URL(), Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := client.KV("secret")
+
+	enforcer, err := casbinv2.NewEnforcer("../../config/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][]string{
+		{"admin", "secrets:*", "read"},
+		{"admin", "secrets:*", "write"},
+		{"user", "secrets:users/{user}/*", "read"},
+		{"user", "secrets:users/{user}/*", "list"},
+		{"user", "secrets:users/{user}/*", "write"},
+		{"user", "secrets:users/{user}/*", "delete"},
+		{"user", "secrets:groups/{group}/*", "read"},
+		{"user", "secrets:groups/{group}/*", "list"},
+	} {
+		if _, err := enforcer.AddPolicy(p[0], p[1], p[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enforcer.AddGroupingPolicy("alice", "user")
+	enforcer.AddGroupingPolicy("bob", "user")
+	enforcer.AddGroupingPolicy("root", "admin")
+	enforcer.AddNamedGroupingPolicy("g2", "alice", "finance")
+
+	previous := casbin.Enforcer
+	casbin.Enforcer = enforcer
+	t.Cleanup(func() { casbin.Enforcer = previous })
+
+	return NewSecretHandler(kv, "app"), kv
+}
+
+// callSecret runs a secret handler as user on path and returns the status
+// and the JSON response
+func callSecret(t *testing.T, h echo.HandlerFunc, method, user, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	return callSecretAs(t, h, method, &auth.CasdoorClaims{Name: user}, path, body)
+}
+
+func callSecretAs(t *testing.T, h echo.HandlerFunc, method string, user *auth.CasdoorClaims, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/secrets/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("*")
+	c.SetParamValues(path)
+	c.Set("user", user)
+
+	if err := h(c); err != nil {
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("%s %s: unexpected error %v", method, path, err)
+		}
+		return httpErr.Code, nil
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: invalid response %q", method, path, rec.Body.String())
+	}
+	return rec.Code, response
+}
+
+func TestSecretOwnFolder(t *testing.T) {
+	h, _ := newSecretHandler(t)
+
+	status, _ := callSecret(t, h.PutSecret, http.MethodPut, "alice", "users/alice/db", `{"data": {"password": "s3cret"}}`)
+	if status != http.StatusOK {
+		t.Fatalf("PUT own secret: status %d, want 200", status)
+	}
+	status, response := callSecret(t, h.GetSecret, http.MethodGet, "alice", "users/alice/db", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET own secret: status %d, want 200", status)
+	}
+	if data, _ := response["data"].(map[string]interface{}); data["password"] != "s3cret" {
+		t.Errorf("GET own secret: data = %v", response["data"])
+	}
+	status, response = callSecret(t, h.GetSecret, http.MethodGet, "alice", "users/alice/", "")
+	if status != http.StatusOK {
+		t.Fatalf("LIST own folder: status %d, want 200", status)
+	}
+	if keys, _ := response["keys"].([]interface{}); len(keys) != 1 || keys[0] != "db" {
+		t.Errorf("LIST own folder: keys = %v", response["keys"])
+	}
+	if status, _ := callSecret(t, h.DeleteSecret, http.MethodDelete, "alice", "users/alice/db", ""); status != http.StatusOK {
+		t.Errorf("DELETE own secret: status %d, want 200", status)
+	}
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", "users/alice/db", ""); status != http.StatusNotFound {
+		t.Errorf("GET deleted secret: status %d, want 404", status)
+	}
+}
+
+func TestSecretOtherUsersFolder(t *testing.T) {
+	h, kv := newSecretHandler(t)
+	if _, err := kv.Put(context.Background(), "app/users/bob/db", map[string]interface{}{"password": "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		handler echo.HandlerFunc
+		method  string
+		path    string
+	}{
+		{h.GetSecret, http.MethodGet, "users/bob/db"},
+		{h.GetSecret, http.MethodGet, "users/bob/"},
+		{h.PutSecret, http.MethodPut, "users/bob/db"},
+		{h.DeleteSecret, http.MethodDelete, "users/bob/db"},
+	} {
+		status, _ := callSecret(t, tc.handler, tc.method, "alice", tc.path, `{"data": {"password": "x"}}`)
+		if status != http.StatusForbidden {
+			t.Errorf("%s %s as alice: status %d, want 403", tc.method, tc.path, status)
+		}
+	}
+
+	// An admin reaches every folder
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "root", "users/bob/db", ""); status != http.StatusOK {
+		t.Errorf("GET users/bob/db as admin: status %d, want 200", status)
+	}
+}
+
+func TestSecretGroupFolder(t *testing.T) {
+	h, kv := newSecretHandler(t)
+	ctx := context.Background()
+	for _, path := range []string{"app/groups/finance/bank", "app/groups/hr/payroll"} {
+		if _, err := kv.Put(ctx, path, map[string]interface{}{"k": "v"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Members of a group read its folder
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", "groups/finance/bank", ""); status != http.StatusOK {
+		t.Errorf("GET own group's secret: status %d, want 200", status)
+	}
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", "groups/finance/", ""); status != http.StatusOK {
+		t.Errorf("LIST own group's folder: status %d, want 200", status)
+	}
+	// ...but may not write it
+	if status, _ := callSecret(t, h.PutSecret, http.MethodPut, "alice", "groups/finance/bank", `{"data": {"k": "x"}}`); status != http.StatusForbidden {
+		t.Errorf("PUT own group's secret: status %d, want 403", status)
+	}
+	// Other groups are out of reach
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", "groups/hr/payroll", ""); status != http.StatusForbidden {
+		t.Errorf("GET another group's secret: status %d, want 403", status)
+	}
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "bob", "groups/finance/bank", ""); status != http.StatusForbidden {
+		t.Errorf("GET a group's secret as non-member: status %d, want 403", status)
+	}
+
+	status, response := callSecret(t, h.GetSecretFolders, http.MethodGet, "alice", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET folders: status %d, want 200", status)
+	}
+	folders, _ := response["folders"].(map[string]interface{})
+	if _, ok := folders["users/alice/"]; !ok || len(folders) != 2 || folders["groups/finance/"] == nil {
+		t.Errorf("GET folders = %v, want users/alice/ and groups/finance/", folders)
+	}
+}
+
+func TestSecretAPIKeyScopes(t *testing.T) {
+	h, kv := newSecretHandler(t)
+	ctx := context.Background()
+	for _, path := range []string{"app/users/alice/db", "app/users/alice/api"} {
+		if _, err := kv.Put(ctx, path, map[string]interface{}{"k": "v"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A key scoped to reading one secret gets nothing else of alice's rights
+	key := &auth.CasdoorClaims{Name: "alice", APIKeyID: "key_1", Scopes: []string{"secrets:users/alice/db:read"}}
+	if status, _ := callSecretAs(t, h.GetSecret, http.MethodGet, key, "users/alice/db", ""); status != http.StatusOK {
+		t.Errorf("GET the scoped secret: status %d, want 200", status)
+	}
+	if status, _ := callSecretAs(t, h.GetSecret, http.MethodGet, key, "users/alice/api", ""); status != http.StatusForbidden {
+		t.Errorf("GET another own secret: status %d, want 403", status)
+	}
+	if status, _ := callSecretAs(t, h.PutSecret, http.MethodPut, key, "users/alice/db", `{"data": {"k": "x"}}`); status != http.StatusForbidden {
+		t.Errorf("PUT the scoped secret: status %d, want 403", status)
+	}
+
+	// Scopes never add to the owner's permissions
+	wide := &auth.CasdoorClaims{Name: "alice", APIKeyID: "key_2", Scopes: []string{"*"}}
+	if status, _ := callSecretAs(t, h.GetSecret, http.MethodGet, wide, "users/alice/api", ""); status != http.StatusOK {
+		t.Errorf("GET own secret with a * key: status %d, want 200", status)
+	}
+	if status, _ := callSecretAs(t, h.GetSecret, http.MethodGet, wide, "users/bob/db", ""); status != http.StatusForbidden {
+		t.Errorf("GET bob's secret with a * key: status %d, want 403", status)
+	}
+}
+
+func TestSecretInvalidPaths(t *testing.T) {
+	h, kv := newSecretHandler(t)
+	if _, err := kv.Put(context.Background(), "app/users/bob/db", map[string]interface{}{"password": "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"users/alice/../bob/db",
+		"users/alice/../../users/bob/db",
+		"users/alice/./db",
+		"users/../users/bob/db",
+		"users/alice//db",
+		"users/alice/db%2F..",
+		"users/alice/a b",
+		"secrets/db",
+		"app/users/alice/db",
+		"users/alice", // a folder, not a key
+		"users",
+	} {
+		if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", path, ""); status != http.StatusBadRequest {
+			t.Errorf("GET %q: status %d, want 400", path, status)
+		}
+		if status, _ := callSecret(t, h.PutSecret, http.MethodPut, "alice", path, `{"data": {"k": "v"}}`); status != http.StatusBadRequest {
+			t.Errorf("PUT %q: status %d, want 400", path, status)
+		}
+	}
+}
+
+func TestSecretWithoutOpenBao(t *testing.T) {
+	h := NewSecretHandler(nil, "app")
+	if status, _ := callSecret(t, h.GetSecret, http.MethodGet, "alice", "users/alice/db", ""); status != http.StatusServiceUnavailable {
+		t.Errorf("GET without OpenBao: status %d, want 503", status)
+	}
+}
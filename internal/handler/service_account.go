@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// serviceAccountName keeps subjects readable and in the same form as
+// mTLS service names
+var serviceAccountName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ServiceAccountHandler issues machine tokens and lets admins manage
+// service accounts and their Casbin policies
+type ServiceAccountHandler struct {
+	accounts repository.ServiceAccountRepository
+}
+
+func NewServiceAccountHandler(accounts repository.ServiceAccountRepository) *ServiceAccountHandler {
+	return &ServiceAccountHandler{accounts: accounts}
+}
+
+// tokenRequest is an OAuth client_credentials token request
+type tokenRequest struct {
+	GrantType    string `json:"grant_type" form:"grant_type"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+}
+
+// IssueToken exchanges client credentials for a service account token
+// POST /api/auth/token
+// Body (form or JSON): grant_type=client_credentials&client_id=...&client_secret=...
+// The credentials may also be sent with HTTP Basic auth
+func (h *ServiceAccountHandler) IssueToken(c echo.Context) error {
+	var req tokenRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if id, secret, ok := c.Request().BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	if req.GrantType != "" && req.GrantType != "client_credentials" {
+		return echo.NewHTTPError(http.StatusBadRequest, "unsupported grant_type (use client_credentials)")
+	}
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "client_id and client_secret are required")
+	}
+
+	ctx := c.Request().Context()
+	token, err := auth.ClientCredentialsLogin(ctx, req.ClientID, req.ClientSecret)
+	if errors.Is(err, auth.ErrInvalidClient) {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	if err != nil {
+		log.Printf("Warning: client_credentials login for %s failed: %v", req.ClientID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to get token from Casdoor")
+	}
+
+	// Reject tokens the API would not accept, instead of failing later
+	claims, err := auth.VerifyToken(token.AccessToken)
+	if err != nil {
+		log.Printf("Warning: Casdoor token for %s does not verify: %v", req.ClientID, err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Casdoor returned a token that does not verify")
+	}
+	if !claims.IsApplication() || claims.ClientID() != req.ClientID {
+		return echo.NewHTTPError(http.StatusBadGateway, "Casdoor returned a token for another client")
+	}
+	claims, account, err := auth.ServiceAccountClaims(ctx, claims)
+	if errors.Is(err, auth.ErrUnknownServiceAccount) || errors.Is(err, auth.ErrServiceAccountDisabled) {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify service account")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":         "Service account token issued",
+		"access_token":    token.AccessToken,
+		"token_type":      "Bearer",
+		"expires_in":      token.ExpiresIn,
+		"service_account": account.Name,
+		"subject":         claims.Name,
+	})
+}
+
+// serviceAccountRequest is the body of create and update requests.
+// Omitted fields keep their current value on update.
+type serviceAccountRequest struct {
+	Name        string   `json:"name"`
+	ClientID    string   `json:"client_id"`
+	Description *string  `json:"description"`
+	Disabled    *bool    `json:"disabled"`
+	Roles       []string `json:"roles"`
+}
+
+// serviceAccountView is a service account with its Casbin subject, roles
+// and direct policies
+type serviceAccountView struct {
+	model.ServiceAccount
+	Subject  string     `json:"subject"`
+	Roles    []string   `json:"roles"`
+	Policies [][]string `json:"policies"`
+}
+
+func viewServiceAccount(account model.ServiceAccount) serviceAccountView {
+	roles, _ := casbin.GetRolesForUser(account.Subject())
+	if roles == nil {
+		roles = []string{}
+	}
+	policies := casbin.GetPoliciesForSubject(account.Subject())
+	if policies == nil {
+		policies = [][]string{}
+	}
+	return serviceAccountView{ServiceAccount: account, Subject: account.Subject(), Roles: roles, Policies: policies}
+}
+
+// GetServiceAccounts lists service accounts
+// GET /api/admin/service-accounts
+func (h *ServiceAccountHandler) GetServiceAccounts(c echo.Context) error {
+	accounts, err := h.accounts.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load service accounts")
+	}
+
+	views := make([]serviceAccountView, 0, len(accounts))
+	for _, account := range accounts {
+		views = append(views, viewServiceAccount(account))
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"service_accounts": views,
+		"count":            len(views),
+		"message":          "Service accounts retrieved",
+	})
+}
+
+// GetServiceAccount returns a service account
+// GET /api/admin/service-accounts/:name
+func (h *ServiceAccountHandler) GetServiceAccount(c echo.Context) error {
+	account, err := h.accounts.Get(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		return serviceAccountError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"service_account": viewServiceAccount(*account),
+		"message":         "Service account retrieved",
+	})
+}
+
+// CreateServiceAccount registers a Casdoor application as a service account
+// POST /api/admin/service-accounts
+// Body: {"name": "billing", "client_id": "<casdoor client id>", "description": "...", "roles": ["warehouse"]}
+func (h *ServiceAccountHandler) CreateServiceAccount(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req serviceAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.ClientID = strings.TrimSpace(req.ClientID)
+	if !serviceAccountName.MatchString(req.Name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "name must be lowercase letters, digits and dashes")
+	}
+	if req.ClientID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "client_id is required")
+	}
+
+	now := time.Now()
+	account := model.ServiceAccount{
+		Name:      req.Name,
+		ClientID:  req.ClientID,
+		CreatedBy: user.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Description != nil {
+		account.Description = *req.Description
+	}
+	if req.Disabled != nil {
+		account.Disabled = *req.Disabled
+	}
+	if err := h.accounts.Create(c.Request().Context(), &account); err != nil {
+		return serviceAccountError(err)
+	}
+
+	for _, role := range req.Roles {
+		if err := casbin.AddRoleForUser(account.Subject(), role); err != nil {
+			log.Printf("Warning: failed to give %s role %s: %v", account.Subject(), role, err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"service_account": viewServiceAccount(account),
+		"message":         "Service account created",
+	})
+}
+
+// UpdateServiceAccount changes the description or disables the account
+// PUT /api/admin/service-accounts/:name
+// Body: {"description": "...", "disabled": true}
+func (h *ServiceAccountHandler) UpdateServiceAccount(c echo.Context) error {
+	var req serviceAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	ctx := c.Request().Context()
+	account, err := h.accounts.Get(ctx, c.Param("name"))
+	if err != nil {
+		return serviceAccountError(err)
+	}
+	if req.Description != nil {
+		account.Description = *req.Description
+	}
+	if req.Disabled != nil {
+		account.Disabled = *req.Disabled
+	}
+	if err := h.accounts.Update(ctx, account); err != nil {
+		return serviceAccountError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"service_account": viewServiceAccount(*account),
+		"message":         "Service account updated",
+	})
+}
+
+// DeleteServiceAccount removes the account with its policies and roles
+// DELETE /api/admin/service-accounts/:name
+func (h *ServiceAccountHandler) DeleteServiceAccount(c echo.Context) error {
+	ctx := c.Request().Context()
+	account, err := h.accounts.Get(ctx, c.Param("name"))
+	if err != nil {
+		return serviceAccountError(err)
+	}
+	if err := h.accounts.Delete(ctx, account.Name); err != nil {
+		return serviceAccountError(err)
+	}
+	if err := casbin.RemoveSubject(account.Subject()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Service account deleted",
+	})
+}
+
+// serviceAccountPolicyRequest is a policy or role of a service account
+type serviceAccountPolicyRequest struct {
+	Object string `json:"object"`
+	Action string `json:"action"`
+	Role   string `json:"role"`
+}
+
+// AddServiceAccountPolicy grants the account a policy or a role
+// POST /api/admin/service-accounts/:name/policies
+// Body: {"object": "orders", "action": "list"} or {"role": "warehouse"}
+func (h *ServiceAccountHandler) AddServiceAccountPolicy(c echo.Context) error {
+	return h.changePolicy(c, casbin.AddPolicy, casbin.AddRoleForUser, "Policy added")
+}
+
+// RemoveServiceAccountPolicy revokes a policy or a role of the account
+// DELETE /api/admin/service-accounts/:name/policies
+// Body: {"object": "orders", "action": "list"} or {"role": "warehouse"}
+func (h *ServiceAccountHandler) RemoveServiceAccountPolicy(c echo.Context) error {
+	return h.changePolicy(c, casbin.RemovePolicy, casbin.DeleteRoleForUser, "Policy removed")
+}
+
+func (h *ServiceAccountHandler) changePolicy(c echo.Context, policy func(sub, obj, act string) error, role func(user, role string) error, message string) error {
+	var req serviceAccountPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	account, err := h.accounts.Get(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		return serviceAccountError(err)
+	}
+
+	switch {
+	case req.Role != "" && req.Object == "" && req.Action == "":
+		err = role(account.Subject(), req.Role)
+	case req.Role == "" && req.Object != "" && req.Action != "":
+		err = policy(account.Subject(), req.Object, req.Action)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "send either object and action, or role")
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"service_account": viewServiceAccount(*account),
+		"message":         message,
+	})
+}
+
+// serviceAccountError maps repository errors to HTTP errors
+func serviceAccountError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "service account not found")
+	case errors.Is(err, repository.ErrDuplicateServiceAccount):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "service account storage failed")
+}
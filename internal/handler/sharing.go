@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type SharingHandler struct {
+	orders       repository.OrderRepository
+	transactions repository.TransactionRepository
+}
+
+func NewSharingHandler(orders repository.OrderRepository, transactions repository.TransactionRepository) *SharingHandler {
+	return &SharingHandler{orders: orders, transactions: transactions}
+}
+
+type grantRequest struct {
+	User      string     `json:"user"`
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ShareOrder shares an order with another user
+// POST /api/orders/:id/shares
+// Body: {"user": "alice", "role": "viewer|editor", "expires_at": "2025-01-31T00:00:00Z"}
+func (h *SharingHandler) ShareOrder(c echo.Context) error {
+	return h.share(c, "orders", h.orderOwner)
+}
+
+// GetOrderShares lists who an order is shared with
+// GET /api/orders/:id/shares
+func (h *SharingHandler) GetOrderShares(c echo.Context) error {
+	return h.listShares(c, "orders", h.orderOwner)
+}
+
+// RevokeOrderShare revokes a user's access to an order
+// DELETE /api/orders/:id/shares/:user
+func (h *SharingHandler) RevokeOrderShare(c echo.Context) error {
+	return h.revokeShare(c, "orders", h.orderOwner)
+}
+
+// ShareTransaction shares a transaction with another user
+// POST /api/transactions/:id/shares
+func (h *SharingHandler) ShareTransaction(c echo.Context) error {
+	return h.share(c, "transactions", h.transactionOwner)
+}
+
+// GetTransactionShares lists who a transaction is shared with
+// GET /api/transactions/:id/shares
+func (h *SharingHandler) GetTransactionShares(c echo.Context) error {
+	return h.listShares(c, "transactions", h.transactionOwner)
+}
+
+// RevokeTransactionShare revokes a user's access to a transaction
+// DELETE /api/transactions/:id/shares/:user
+func (h *SharingHandler) RevokeTransactionShare(c echo.Context) error {
+	return h.revokeShare(c, "transactions", h.transactionOwner)
+}
+
+func (h *SharingHandler) orderOwner(ctx context.Context, id string) (string, error) {
+	order, err := h.orders.Get(ctx, id)
+	if err != nil {
+		return "", orderError(err)
+	}
+	return order.UserID, nil
+}
+
+func (h *SharingHandler) transactionOwner(ctx context.Context, id string) (string, error) {
+	txn, err := h.transactions.Get(ctx, id)
+	if err != nil {
+		return "", transactionError(err)
+	}
+	return txn.UserID, nil
+}
+
+// shareTarget loads the resource owner and checks the caller may manage
+// its grants: only the owner or an admin can
+func shareTarget(c echo.Context, owner func(ctx context.Context, id string) (string, error)) (*auth.CasdoorClaims, string, error) {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return nil, "", echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	ownerID, err := owner(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return nil, "", err
+	}
+	if !user.IsAdmin && ownerID != user.Name {
+		return nil, "", echo.NewHTTPError(http.StatusForbidden, "only the owner can manage sharing")
+	}
+	return user, ownerID, nil
+}
+
+func (h *SharingHandler) share(c echo.Context, resource string, owner func(ctx context.Context, id string) (string, error)) error {
+	user, ownerID, err := shareTarget(c, owner)
+	if err != nil {
+		return err
+	}
+
+	var req grantRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.User == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "user is required")
+	}
+	if req.User == ownerID {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot share with the owner")
+	}
+	if !casbin.IsValidShareRole(req.Role) {
+		return echo.NewHTTPError(http.StatusBadRequest, "role must be viewer or editor")
+	}
+	if err := validateExpiry(req.ExpiresAt); err != nil {
+		return err
+	}
+
+	grant, err := casbin.Share(resource, c.Param("id"), req.User, req.Role, user.Name, req.ExpiresAt)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to share: "+err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"grant":     grant,
+		"message":   "Shared with " + req.User + " as " + req.Role,
+		"shared_by": user.Name,
+	})
+}
+
+func (h *SharingHandler) listShares(c echo.Context, resource string, owner func(ctx context.Context, id string) (string, error)) error {
+	user, _, err := shareTarget(c, owner)
+	if err != nil {
+		return err
+	}
+
+	grants := casbin.GrantsOn(casbin.ResourceObject(resource, c.Param("id")))
+	if grants == nil {
+		grants = []casbin.Grant{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"shares":      grants,
+		"count":       len(grants),
+		"accessed_by": user.Name,
+	})
+}
+
+func (h *SharingHandler) revokeShare(c echo.Context, resource string, owner func(ctx context.Context, id string) (string, error)) error {
+	user, _, err := shareTarget(c, owner)
+	if err != nil {
+		return err
+	}
+
+	if err := casbin.Revoke(c.Param("user"), casbin.ResourceObject(resource, c.Param("id"))); err != nil {
+		return grantError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "Access of " + c.Param("user") + " revoked",
+		"revoked_by": user.Name,
+	})
+}
+
+// GetDelegations lists delegations given and received by the current user
+// GET /api/delegations
+func (h *SharingHandler) GetDelegations(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	given := casbin.DelegationsFrom(user.Name)
+	received := casbin.DelegationsTo(user.Name)
+	if given == nil {
+		given = []casbin.Grant{}
+	}
+	if received == nil {
+		received = []casbin.Grant{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"given":    given,
+		"received": received,
+		"user":     user.Name,
+	})
+}
+
+// CreateDelegation lets another user act for the current user: they get
+// editor access to all of the user's orders and transactions and may
+// create them on the user's behalf. Admins may pass "principal".
+// POST /api/delegations
+// Body: {"delegate": "assistant", "expires_at": "2025-01-31T00:00:00Z"}
+func (h *SharingHandler) CreateDelegation(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req struct {
+		Delegate  string     `json:"delegate"`
+		Principal string     `json:"principal"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	if req.Principal == "" {
+		req.Principal = user.Name
+	}
+	if req.Principal != user.Name && !user.IsAdmin {
+		return echo.NewHTTPError(http.StatusForbidden, "can only delegate your own account")
+	}
+	if req.Delegate == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "delegate is required")
+	}
+	if req.Delegate == req.Principal {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot delegate to yourself")
+	}
+	if err := validateExpiry(req.ExpiresAt); err != nil {
+		return err
+	}
+
+	grant, err := casbin.Delegate(req.Principal, req.Delegate, user.Name, req.ExpiresAt)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delegate: "+err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"delegation": grant,
+		"message":    req.Delegate + " may now act for " + req.Principal,
+		"created_by": user.Name,
+	})
+}
+
+// RevokeDelegation ends a delegation. The principal revokes it, the
+// delegate may give it up (?principal=), and admins may do either.
+// DELETE /api/delegations/:delegate
+func (h *SharingHandler) RevokeDelegation(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	delegate := c.Param("delegate")
+	principal := c.QueryParam("principal")
+	if principal == "" {
+		principal = user.Name
+	}
+	if !user.IsAdmin && principal != user.Name && delegate != user.Name {
+		return echo.NewHTTPError(http.StatusForbidden, "can only revoke your own delegations")
+	}
+
+	if err := casbin.Revoke(delegate, casbin.DelegationObject(principal)); err != nil {
+		return grantError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    delegate + " may no longer act for " + principal,
+		"revoked_by": user.Name,
+	})
+}
+
+func validateExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_at must be in the future")
+	}
+	return nil
+}
+
+func grantError(err error) error {
+	if errors.Is(err, casbin.ErrGrantNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "grant not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke: "+err.Error())
+}
+
+// actingFor resolves the owner of a new record: the caller, or onBehalfOf
+// when the caller holds a delegation from that user
+func actingFor(user *auth.CasdoorClaims, onBehalfOf string) (string, error) {
+	if onBehalfOf == "" || onBehalfOf == user.Name {
+		return user.Name, nil
+	}
+	if !casbin.CanActFor(user.Name, onBehalfOf) {
+		return "", echo.NewHTTPError(http.StatusForbidden, "no delegation to act for "+onBehalfOf)
+	}
+	return onBehalfOf, nil
+}
@@ -1,97 +1,101 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
 	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/casbin"
+	"casdoor-casbin-openbao/internal/ledger"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
 	"github.com/labstack/echo/v4"
 )
 
-type TransactionHandler struct{}
-
-func NewTransactionHandler() *TransactionHandler {
-	return &TransactionHandler{}
-}
-
-type Transaction struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	Amount      float64   `json:"amount"`
-	Type        string    `json:"type"`
-	Status      string    `json:"status"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   string    `json:"created_by"`
+type TransactionHandler struct {
+	transactions repository.TransactionRepository
+	ledger       *ledger.Service
 }
 
-// Mock data - In real app, this would be from database
-var transactions = []Transaction{
-	{
-		ID:          "txn_001",
-		UserID:      "admin",
-		Amount:      1000.50,
-		Type:        "deposit",
-		Status:      "completed",
-		Description: "Initial deposit",
-		CreatedAt:   time.Now().Add(-24 * time.Hour),
-		CreatedBy:   "admin",
-	},
-	{
-		ID:          "txn_002", 
-		UserID:      "hihi",
-		Amount:      -250.00,
-		Type:        "withdrawal",
-		Status:      "pending",
-		Description: "ATM withdrawal",
-		CreatedAt:   time.Now().Add(-2 * time.Hour),
-		CreatedBy:   "hihi",
-	},
-	{
-		ID:          "txn_003",
-		UserID:      "testuser",
-		Amount:      500.75,
-		Type:        "transfer",
-		Status:      "completed", 
-		Description: "Transfer from savings",
-		CreatedAt:   time.Now().Add(-1 * time.Hour),
-		CreatedBy:   "testuser",
-	},
+func NewTransactionHandler(transactions repository.TransactionRepository, ledger *ledger.Service) *TransactionHandler {
+	return &TransactionHandler{transactions: transactions, ledger: ledger}
 }
 
-// GetTransactions returns all transactions (admin only)
+// GetTransactions returns the transactions the caller may read. The caller's Casbin
+// data-scope policies (("transactions", "scope:...")) become part of the query,
+// so admins see everything and other roles see only their rows.
 func (h *TransactionHandler) GetTransactions(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return err
+	}
+	if opts.Filter.Scope, err = dataScope(user, "transactions"); err != nil {
+		return err
+	}
+
+	page, err := h.transactions.List(c.Request().Context(), opts)
+	if err != nil {
+		return listError(err, "transactions")
+	}
+	resolver := newAccessResolver(user, "transactions")
+	for i := range page.Items {
+		page.Items[i].Access = resolver.access(page.Items[i].ID, page.Items[i].UserID, page.Items[i].Status)
+	}
+
+	transactions, err := shapeFields(c.Request().Context(), user, "transactions", page.Items)
+	if err != nil {
+		return err
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"transactions": transactions,
-		"total":        len(transactions),
-		"message":      "All transactions retrieved",
+		"count":        len(page.Items),
+		"next_cursor":  page.NextCursor,
+		"message":      "Transactions retrieved",
 		"accessed_by":  user.Name,
 	})
 }
 
-// GetMyTransactions returns current user's transactions only
+// GetMyTransactions returns the current user's transactions, those of
+// users who delegated to them and transactions shared with them, each
+// with an "access" flag; ?user= narrows to one owner
 func (h *TransactionHandler) GetMyTransactions(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
-	var userTransactions []Transaction
-	for _, txn := range transactions {
-		if txn.UserID == user.Name {
-			userTransactions = append(userTransactions, txn)
-		}
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return err
+	}
+	opts.Filter.Scope = ownScope(user.Name, "transactions")
+
+	page, err := h.transactions.List(c.Request().Context(), opts)
+	if err != nil {
+		return listError(err, "transactions")
+	}
+	resolver := newAccessResolver(user, "transactions")
+	for i := range page.Items {
+		page.Items[i].Access = resolver.access(page.Items[i].ID, page.Items[i].UserID, page.Items[i].Status)
+	}
+
+	transactions, err := shapeFields(c.Request().Context(), user, "transactions", page.Items)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"transactions": userTransactions,
-		"total":        len(userTransactions),
+		"transactions": transactions,
+		"count":        len(page.Items),
+		"next_cursor":  page.NextCursor,
 		"message":      "Your transactions retrieved",
 		"user":         user.Name,
 	})
@@ -104,27 +108,34 @@ func (h *TransactionHandler) GetTransaction(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
 	}
 
-	txnID := c.Param("id")
-	
-	for _, txn := range transactions {
-		if txn.ID == txnID {
-			// Check ownership - user can only see their own transactions unless admin
-			if !user.IsAdmin && txn.UserID != user.Name {
-				return echo.NewHTTPError(http.StatusForbidden, "can only access your own transactions")
-			}
-			
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"transaction": txn,
-				"message":     "Transaction retrieved",
-				"accessed_by": user.Name,
-			})
-		}
+	txn, err := h.transactions.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return transactionError(err)
+	}
+
+	txn.Access = resourceAccess(user, "transactions", txn.ID, txn.UserID, txn.Status)
+	if txn.Access == nil {
+		return echo.NewHTTPError(http.StatusForbidden, "no access to this transaction")
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "transactions", txn)
+	if err != nil {
+		return err
 	}
 
-	return echo.NewHTTPError(http.StatusNotFound, "transaction not found")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"transaction": shaped,
+		"message":     "Transaction retrieved",
+		"accessed_by": user.Name,
+	})
 }
 
-// CreateTransaction creates a new transaction
+// CreateTransaction creates a transaction and posts it to the ledger
+// POST /api/transactions
+// Body: {"amount": "100.00", "currency": "USD", "type": "deposit|withdrawal|transfer", "counterparty": "bob", "on_behalf_of": "manager"}
+// Amounts are positive decimal strings; the type decides the direction.
+// on_behalf_of requires a delegation from that user. Deposits require
+// ("transactions", "deposit").
 func (h *TransactionHandler) CreateTransaction(c echo.Context) error {
 	user, ok := auth.GetUserFromContext(c)
 	if !ok {
@@ -132,31 +143,185 @@ func (h *TransactionHandler) CreateTransaction(c echo.Context) error {
 	}
 
 	var req struct {
-		Amount      float64 `json:"amount"`
-		Type        string  `json:"type"`
-		Description string  `json:"description"`
+		Amount       string `json:"amount"`
+		Currency     string `json:"currency"`
+		Type         string `json:"type"`
+		Description  string `json:"description"`
+		Counterparty string `json:"counterparty"`
+		OnBehalfOf   string `json:"on_behalf_of"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
 
-	newTxn := Transaction{
-		ID:          "txn_" + strconv.FormatInt(time.Now().Unix(), 10),
-		UserID:      user.Name,
-		Amount:      req.Amount,
-		Type:        req.Type,
-		Status:      "pending",
-		Description: req.Description,
-		CreatedAt:   time.Now(),
-		CreatedBy:   user.Name,
+	if req.Currency == "" {
+		req.Currency = model.DefaultCurrency
+	}
+	amount, err := money.Parse(req.Amount, req.Currency)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid amount: "+err.Error())
+	}
+
+	// Payments and refunds are only created through orders
+	if model.IsOrderTransactionType(req.Type) {
+		return echo.NewHTTPError(http.StatusBadRequest, req.Type+" transactions are created through orders")
+	}
+
+	fields := []string{"amount", "type"}
+	if req.Description != "" {
+		fields = append(fields, "description")
+	}
+	if req.Counterparty != "" {
+		fields = append(fields, "counterparty")
+	}
+	if err := checkFieldWrites(user, "transactions", fields...); err != nil {
+		return err
 	}
 
-	transactions = append(transactions, newTxn)
+	// Deposits credit the account from outside the ledger, so they need
+	// their own permission
+	if req.Type == model.TransactionTypeDeposit {
+		allowed, err := casbin.EnforceUser(user, "transactions", "deposit")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "authorization check failed")
+		}
+		if !allowed {
+			return echo.NewHTTPError(http.StatusForbidden, "not allowed to create deposits")
+		}
+	}
+
+	owner, err := actingFor(user, req.OnBehalfOf)
+	if err != nil {
+		return err
+	}
+
+	newTxn := model.Transaction{
+		ID:           model.NewID("txn"),
+		UserID:       owner,
+		Amount:       amount,
+		Type:         req.Type,
+		Status:       model.TransactionStatusPending,
+		Description:  req.Description,
+		Counterparty: req.Counterparty,
+		CreatedAt:    time.Now(),
+		CreatedBy:    user.Name,
+	}
+
+	if err := h.ledger.Record(c.Request().Context(), &newTxn); err != nil {
+		return postingError(err)
+	}
+
+	message := "Transaction created successfully"
+	if newTxn.Status == model.TransactionStatusPendingApproval {
+		message = "Transaction is above the approval threshold and awaits approval"
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "transactions", newTxn)
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"transaction": newTxn,
-		"message":     "Transaction created successfully",
+		"transaction": shaped,
+		"message":     message,
 		"created_by":  user.Name,
 	})
-}
\ No newline at end of file
+}
+
+// transactionError maps repository errors to HTTP errors
+func transactionError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "transaction not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to load transaction")
+}
+
+// ApproveTransaction approves and posts a transaction waiting for approval
+// POST /api/transactions/:id/approve
+// Body: {"note": "..."} (optional)
+func (h *TransactionHandler) ApproveTransaction(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := checkFieldWrites(user, "transactions", "status"); err != nil {
+		return err
+	}
+
+	txn, err := h.ledger.Approve(c.Request().Context(), c.Param("id"), user.Name, req.Note)
+	if err != nil {
+		return postingError(err)
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "transactions", txn)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"transaction": shaped,
+		"message":     "Transaction approved and posted",
+		"approved_by": user.Name,
+	})
+}
+
+// RejectTransaction rejects a transaction waiting for approval
+// POST /api/transactions/:id/reject
+// Body: {"reason": "..."}
+func (h *TransactionHandler) RejectTransaction(c echo.Context) error {
+	user, ok := auth.GetUserFromContext(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := checkFieldWrites(user, "transactions", "status"); err != nil {
+		return err
+	}
+
+	txn, err := h.ledger.Reject(c.Request().Context(), c.Param("id"), user.Name, req.Reason)
+	if err != nil {
+		return postingError(err)
+	}
+
+	shaped, err := shapeFields(c.Request().Context(), user, "transactions", txn)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"transaction": shaped,
+		"message":     "Transaction rejected",
+		"rejected_by": user.Name,
+	})
+}
+
+// postingError maps ledger errors to HTTP errors
+func postingError(err error) error {
+	switch {
+	case errors.Is(err, ledger.ErrInvalidTransaction):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrInsufficientFunds):
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "insufficient funds")
+	case errors.Is(err, repository.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "transaction not found")
+	case errors.Is(err, ledger.ErrSelfReview):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, ledger.ErrNotPendingApproval), errors.Is(err, repository.ErrConflict):
+		return echo.NewHTTPError(http.StatusConflict, "transaction is not pending approval")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "failed to post transaction")
+}
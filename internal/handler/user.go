@@ -74,21 +74,3 @@ func (h *UserHandler) ProtectedResource(c echo.Context) error {
 	})
 }
 
-// GetSecrets returns secrets from vault (demonstrates cert verification)
-// GET /api/secrets
-func (h *UserHandler) GetSecrets(c echo.Context) error {
-	// Token is verified by AuthMiddleware using Casdoor cert
-	// This is where cert verification happens!
-	user, ok := auth.GetUserFromContext(c)
-	if !ok {
-		return echo.NewHTTPError(http.StatusUnauthorized, "user not authenticated")
-	}
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "Token verified using Casdoor cert in middleware",
-		"user":    user.Name,
-		"secrets": []string{"secret1", "secret2", "secret3"},
-		"note":    "This endpoint required cert verification via middleware",
-	})
-}
-
@@ -0,0 +1,141 @@
+// Package idempotency replays stored responses for retried requests that
+// carry the same Idempotency-Key header.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/fieldcrypt"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// keyTTL is how long a stored response is replayed before the key can be reused
+	keyTTL = 24 * time.Hour
+)
+
+// Middleware stores the first successful response per Idempotency-Key and
+// user, and replays it for retries with an identical body. Reusing a key
+// with a different payload returns 409 Conflict. Requests without the
+// header are passed through unchanged. Stored responses are encrypted with
+// the field encryption key, since they hold the decrypted fields of what
+// was created.
+func Middleware(store repository.IdempotencyRepository) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+			}
+
+			user, ok := auth.GetUserFromContext(c)
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "user not found in context")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			rec := &model.IdempotencyRecord{
+				UserID:      user.Name,
+				Key:         key,
+				RequestHash: requestHash(c.Request().Method, c.Request().URL.Path, body),
+				CreatedAt:   time.Now(),
+			}
+
+			existing, err := store.Begin(ctx, rec)
+			if err == nil && existing != nil && time.Since(existing.CreatedAt) > keyTTL {
+				// Expired key: drop the old record and claim it again
+				if err = store.Release(ctx, user.Name, key); err == nil {
+					existing, err = store.Begin(ctx, rec)
+				}
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to check Idempotency-Key")
+			}
+
+			if existing != nil {
+				if existing.RequestHash != rec.RequestHash {
+					return echo.NewHTTPError(http.StatusConflict, "Idempotency-Key was already used with a different request")
+				}
+				if !existing.Completed() {
+					return echo.NewHTTPError(http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+				}
+				body, err := fieldcrypt.Decrypt(ctx, string(existing.ResponseBody))
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError, "failed to read the stored response")
+				}
+				c.Response().Header().Set(HeaderReplayed, "true")
+				return c.Blob(existing.StatusCode, existing.ContentType, []byte(body))
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			err = next(c)
+
+			// Only successful responses are stored; anything else releases
+			// the key so the client can retry.
+			status := c.Response().Status
+			if err != nil || status < 200 || status >= 300 {
+				if releaseErr := store.Release(ctx, user.Name, key); releaseErr != nil {
+					log.Printf("Warning: failed to release Idempotency-Key: %v", releaseErr)
+				}
+				return err
+			}
+
+			// Plain text is never stored: if encryption fails the key stays
+			// in progress, as when storing fails, rather than allow a duplicate
+			stored, err := fieldcrypt.Encrypt(ctx, recorder.body.String())
+			if err == nil {
+				contentType := c.Response().Header().Get(echo.HeaderContentType)
+				err = store.Complete(ctx, user.Name, key, status, contentType, []byte(stored))
+			}
+			if err != nil {
+				log.Printf("Warning: failed to store idempotent response: %v", err)
+			}
+			return nil
+		}
+	}
+}
+
+// requestHash fingerprints a request so a reused key can be detected. The
+// path is the request URL, not the route, so the same key on another
+// resource is a different request.
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body while writing it to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"casdoor-casbin-openbao/internal/auth"
+	"casdoor-casbin-openbao/internal/fieldcrypt"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/openbao"
+	"casdoor-casbin-openbao/internal/openbao/openbaotest"
+	"casdoor-casbin-openbao/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+// newServer routes POST /api/orders/:id/refunds through the middleware and
+// counts the refunds the handler actually made per order
+func newServer() (*echo.Echo, map[string]int) {
+	refunds := map[string]int{}
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", &auth.CasdoorClaims{Name: "alice"})
+			return next(c)
+		}
+	})
+	e.POST("/api/orders/:id/refunds", func(c echo.Context) error {
+		refunds[c.Param("id")]++
+		return c.JSON(http.StatusCreated, map[string]string{"order_id": c.Param("id")})
+	}, Middleware(repository.NewMemoryIdempotencyRepository()))
+	return e, refunds
+}
+
+func post(e *echo.Echo, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIdempotencyKey, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	e, refunds := newServer()
+	body := `{"amount": 10}`
+
+	first := post(e, "/api/orders/ord_1/refunds", "k1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want 201", first.Code)
+	}
+	retry := post(e, "/api/orders/ord_1/refunds", "k1", body)
+	if retry.Code != http.StatusCreated || retry.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("retry: status %d, replayed %q; want a replayed 201", retry.Code, retry.Header().Get(HeaderReplayed))
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retry body = %q, want %q", retry.Body.String(), first.Body.String())
+	}
+	if refunds["ord_1"] != 1 {
+		t.Errorf("ord_1 refunded %d times, want 1", refunds["ord_1"])
+	}
+
+	if rec := post(e, "/api/orders/ord_1/refunds", "k1", `{"amount": 20}`); rec.Code != http.StatusConflict {
+		t.Errorf("same key with another body: status %d, want 409", rec.Code)
+	}
+}
+
+func TestSameKeyOnAnotherResource(t *testing.T) {
+	e, refunds := newServer()
+	body := `{"amount": 10}`
+
+	if rec := post(e, "/api/orders/ord_1/refunds", "k1", body); rec.Code != http.StatusCreated {
+		t.Fatalf("first order: status %d, want 201", rec.Code)
+	}
+	// Same key and body on a different order is not a retry of the first
+	rec := post(e, "/api/orders/ord_2/refunds", "k1", body)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("second order: status %d, want 409", rec.Code)
+	}
+	if rec.Header().Get(HeaderReplayed) != "" {
+		t.Error("second order got the first order's replayed response")
+	}
+	if refunds["ord_1"] != 1 || refunds["ord_2"] != 0 {
+		t.Errorf("refunds = %v, want only ord_1 refunded once", refunds)
+	}
+}
+
+func TestStoredResponseIsEncrypted(t *testing.T) {
+	fake := openbaotest.NewFake("root")
+	t.Cleanup(fake.Close)
+	fake.MountTransit("transit")
+	client, err := openbao.NewClient(openbao.Config{Address: fake.URL(), Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transit := client.Transit("transit")
+	if err := transit.CreateKey(context.Background(), "fields"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fieldcrypt.Configure(transit, "fields", fieldcrypt.DecryptAlways); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fieldcrypt.Configure(nil, "", "") })
+
+	store := repository.NewMemoryIdempotencyRepository()
+	e := echo.New()
+	e.POST("/api/orders", func(c echo.Context) error {
+		c.Set("user", &auth.CasdoorClaims{Name: "alice"})
+		return Middleware(store)(func(c echo.Context) error {
+			return c.JSON(http.StatusCreated, map[string]string{"shipping_address": "221B Baker Street"})
+		})(c)
+	})
+
+	first := post(e, "/api/orders", "k1", `{}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want 201", first.Code)
+	}
+
+	stored, err := store.Begin(context.Background(), &model.IdempotencyRecord{UserID: "alice", Key: "k1"})
+	if err != nil || stored == nil {
+		t.Fatalf("stored record = %v, %v", stored, err)
+	}
+	if body := string(stored.ResponseBody); !fieldcrypt.IsCiphertext(body) || strings.Contains(body, "Baker") {
+		t.Errorf("stored response = %q, want a ciphertext", body)
+	}
+
+	retry := post(e, "/api/orders", "k1", `{}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry: status %d, body %q; want the first response %q", retry.Code, retry.Body.String(), first.Body.String())
+	}
+}
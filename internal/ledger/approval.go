@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// defaultRole holds thresholds that apply regardless of role
+const defaultRole = "*"
+
+// Thresholds are the amounts above which a transaction needs approval,
+// per role and currency
+type Thresholds map[string]map[string]money.Money
+
+// ParseThresholds reads a list of "[role:]CURRENCY=amount" entries, e.g.
+// "USD=10000.00,vip:USD=50000.00". Entries without a role are defaults.
+func ParseThresholds(s string) (Thresholds, error) {
+	thresholds := Thresholds{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, amount, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid approval threshold %q", entry)
+		}
+		role, currency, ok := strings.Cut(key, ":")
+		if !ok {
+			role, currency = defaultRole, key
+		}
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+
+		limit, err := money.Parse(amount, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approval threshold %q: %w", entry, err)
+		}
+		if limit.IsNegative() {
+			return nil, fmt.Errorf("invalid approval threshold %q: must not be negative", entry)
+		}
+
+		role = strings.TrimSpace(role)
+		if thresholds[role] == nil {
+			thresholds[role] = map[string]money.Money{}
+		}
+		thresholds[role][currency] = limit
+	}
+	return thresholds, nil
+}
+
+// Limit returns the approval threshold for a user with roles in currency.
+// Role-specific thresholds take precedence over defaults; if several roles
+// have one, the highest applies. ok is false when no threshold is
+// configured for the currency, in which case no approval is required.
+func (t Thresholds) Limit(currency string, roles []string) (limit money.Money, ok bool) {
+	for _, role := range roles {
+		candidate, found := t[role][currency]
+		if !found {
+			continue
+		}
+		if !ok || candidate.Minor > limit.Minor {
+			limit, ok = candidate, true
+		}
+	}
+	if ok {
+		return limit, true
+	}
+
+	limit, ok = t[defaultRole][currency]
+	return limit, ok
+}
+
+// ApprovalPolicy decides which transactions need a second person to
+// approve them before they are posted
+type ApprovalPolicy struct {
+	Thresholds Thresholds
+	// Roles returns the Casbin roles of a user
+	Roles func(user string) ([]string, error)
+}
+
+// requiresApproval reports whether amount, created by user, is above the
+// user's threshold
+func (p ApprovalPolicy) requiresApproval(user string, amount money.Money) (bool, error) {
+	var roles []string
+	if p.Roles != nil {
+		var err error
+		if roles, err = p.Roles(user); err != nil {
+			return false, err
+		}
+	}
+
+	limit, ok := p.Thresholds.Limit(amount.Currency, roles)
+	if !ok {
+		return false, nil
+	}
+	return amount.Minor > limit.Minor, nil
+}
@@ -0,0 +1,226 @@
+// Package ledger posts transactions to accounts as balanced double-entry
+// ledger entries.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+var (
+	// ErrInvalidTransaction is returned when a transaction fails validation
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// ErrNotPendingApproval is returned when reviewing a transaction that is not waiting for approval
+	ErrNotPendingApproval = errors.New("transaction is not pending approval")
+	// ErrSelfReview is returned when the creator or account owner of a transaction tries to review it
+	ErrSelfReview = errors.New("cannot review your own transaction")
+)
+
+// Service records transactions and posts them to the ledger
+type Service struct {
+	transactor   repository.Transactor
+	accounts     repository.AccountRepository
+	transactions repository.TransactionRepository
+	approval     ApprovalPolicy
+}
+
+// NewService creates a ledger service
+func NewService(transactor repository.Transactor, accounts repository.AccountRepository, transactions repository.TransactionRepository, approval ApprovalPolicy) *Service {
+	return &Service{
+		transactor:   transactor,
+		accounts:     accounts,
+		transactions: transactions,
+		approval:     approval,
+	}
+}
+
+// Validate checks the type, the sign convention and the counterparty of txn
+func Validate(txn *model.Transaction) error {
+	if !model.IsValidTransactionType(txn.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidTransaction, txn.Type)
+	}
+	// Amounts are always positive; the type decides the direction
+	if !money.ValidCurrency(txn.Amount.Currency) {
+		return fmt.Errorf("%w: unknown currency %q", ErrInvalidTransaction, txn.Amount.Currency)
+	}
+	if !txn.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidTransaction)
+	}
+
+	if model.IsOrderTransactionType(txn.Type) != (txn.OrderID != "") {
+		return fmt.Errorf("%w: only payments and refunds reference an order", ErrInvalidTransaction)
+	}
+
+	if txn.Type == model.TransactionTypeTransfer {
+		if txn.Counterparty == "" {
+			return fmt.Errorf("%w: transfer requires a counterparty", ErrInvalidTransaction)
+		}
+		if txn.Counterparty == txn.UserID {
+			return fmt.Errorf("%w: cannot transfer to yourself", ErrInvalidTransaction)
+		}
+	} else if txn.Counterparty != "" {
+		return fmt.Errorf("%w: only transfers have a counterparty", ErrInvalidTransaction)
+	}
+	return nil
+}
+
+// Record validates txn, stores it and posts its entries in one database
+// transaction. On success the transaction is completed; if posting fails
+// (e.g. repository.ErrInsufficientFunds) nothing is stored. Transactions
+// above the creator's approval threshold are stored as pending_approval
+// and only posted once approved.
+func (s *Service) Record(ctx context.Context, txn *model.Transaction) error {
+	if err := Validate(txn); err != nil {
+		return err
+	}
+
+	// Payments and refunds follow the order flow and skip maker-checker
+	needsApproval := false
+	if !model.IsOrderTransactionType(txn.Type) {
+		var err error
+		if needsApproval, err = s.approval.requiresApproval(txn.CreatedBy, txn.Amount); err != nil {
+			return err
+		}
+	}
+
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		account, err := s.accounts.GetOrCreate(ctx, txn.UserID, model.AccountKindUser, txn.Amount.Currency)
+		if err != nil {
+			return err
+		}
+		txn.AccountID = account.ID
+
+		if needsApproval {
+			txn.Status = model.TransactionStatusPendingApproval
+			return s.transactions.Create(ctx, txn)
+		}
+
+		entries, err := s.entries(ctx, txn)
+		if err != nil {
+			return err
+		}
+
+		if err := s.accounts.Post(ctx, entries); err != nil {
+			return err
+		}
+
+		txn.Status = model.TransactionStatusCompleted
+		return s.transactions.Create(ctx, txn)
+	})
+}
+
+// Approve posts a pending_approval transaction and marks it completed.
+// The approver must not be the creator.
+func (s *Service) Approve(ctx context.Context, id, approver, note string) (*model.Transaction, error) {
+	txn, err := s.pendingReview(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	var approved *model.Transaction
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		entries, err := s.entries(ctx, txn)
+		if err != nil {
+			return err
+		}
+		if err := s.accounts.Post(ctx, entries); err != nil {
+			return err
+		}
+		approved, err = s.transactions.Review(ctx, txn.ID, model.TransactionStatusPendingApproval, model.TransactionStatusCompleted, approver, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return approved, nil
+}
+
+// Reject marks a pending_approval transaction rejected without posting it.
+// The reviewer must not be the creator.
+func (s *Service) Reject(ctx context.Context, id, reviewer, reason string) (*model.Transaction, error) {
+	txn, err := s.pendingReview(ctx, id, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.transactions.Review(ctx, txn.ID, model.TransactionStatusPendingApproval, model.TransactionStatusRejected, reviewer, reason)
+}
+
+// pendingReview loads a transaction that reviewer may approve or reject
+func (s *Service) pendingReview(ctx context.Context, id, reviewer string) (*model.Transaction, error) {
+	txn, err := s.transactions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if txn.Status != model.TransactionStatusPendingApproval {
+		return nil, ErrNotPendingApproval
+	}
+	// A delegate may create a transaction on the reviewer's account; the
+	// account owner must not approve it either
+	if txn.CreatedBy == reviewer || txn.UserID == reviewer {
+		return nil, ErrSelfReview
+	}
+	return txn, nil
+}
+
+// entries builds the balanced debit/credit pair for txn
+func (s *Service) entries(ctx context.Context, txn *model.Transaction) ([]model.LedgerEntry, error) {
+	var debit, credit string
+
+	switch txn.Type {
+	case model.TransactionTypeDeposit:
+		external, err := s.accounts.GetOrCreate(ctx, model.ExternalAccountOwner, model.AccountKindSystem, txn.Amount.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, credit = external.ID, txn.AccountID
+	case model.TransactionTypeWithdrawal:
+		external, err := s.accounts.GetOrCreate(ctx, model.ExternalAccountOwner, model.AccountKindSystem, txn.Amount.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, credit = txn.AccountID, external.ID
+	case model.TransactionTypeTransfer:
+		recipient, err := s.accounts.GetOrCreate(ctx, txn.Counterparty, model.AccountKindUser, txn.Amount.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, credit = txn.AccountID, recipient.ID
+	case model.TransactionTypePayment:
+		merchant, err := s.accounts.GetOrCreate(ctx, model.MerchantAccountOwner, model.AccountKindSystem, txn.Amount.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, credit = txn.AccountID, merchant.ID
+	case model.TransactionTypeRefund:
+		merchant, err := s.accounts.GetOrCreate(ctx, model.MerchantAccountOwner, model.AccountKindSystem, txn.Amount.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, credit = merchant.ID, txn.AccountID
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidTransaction, txn.Type)
+	}
+
+	return []model.LedgerEntry{
+		{TransactionID: txn.ID, AccountID: debit, Direction: model.EntryDebit, Amount: txn.Amount},
+		{TransactionID: txn.ID, AccountID: credit, Direction: model.EntryCredit, Amount: txn.Amount},
+	}, nil
+}
+
+// Balance returns an account and its ledger entries
+func (s *Service) Balance(ctx context.Context, accountID string) (*model.Account, []model.LedgerEntry, error) {
+	account, err := s.accounts.Get(ctx, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := s.accounts.ListEntries(ctx, accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, entries, nil
+}
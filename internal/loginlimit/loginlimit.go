@@ -0,0 +1,272 @@
+// Package loginlimit slows down password guessing on direct logins. Failed
+// logins are counted per username and per client IP; each failure doubles
+// the wait before the next attempt, and too many failures lock the
+// username or IP out for a while.
+package loginlimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/metrics"
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/repository"
+)
+
+// Counter backends (LOGIN_LIMIT_BACKEND)
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+var (
+	failures = metrics.NewCounter("login_failures_total",
+		"Failed direct logins")
+	blocked = metrics.NewCounter("login_blocked_total",
+		"Direct logins refused by backoff or lockout")
+	lockouts = metrics.NewCounter("login_lockouts_total",
+		"Usernames and client IPs locked out after too many failures")
+)
+
+// BlockedError is returned by Begin while a username or IP must wait
+type BlockedError struct {
+	RetryAfter time.Duration
+	// Locked is true for a lockout, false for backoff between attempts
+	Locked bool
+}
+
+func (e *BlockedError) Error() string {
+	wait := time.Duration(e.Seconds()) * time.Second
+	if e.Locked {
+		return fmt.Sprintf("too many failed logins; locked for %s", wait)
+	}
+	return fmt.Sprintf("too many failed logins; retry in %s", wait)
+}
+
+// Seconds is RetryAfter rounded up to whole seconds, for Retry-After
+func (e *BlockedError) Seconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Limiter decides whether a login may be attempted and records outcomes
+type Limiter struct {
+	repo repository.LoginFailureRepository
+	cfg  config.LoginLimitConfig
+
+	lastPrune atomic.Int64
+}
+
+// New creates a Limiter that keeps its counters in repo
+func New(repo repository.LoginFailureRepository, cfg config.LoginLimitConfig) *Limiter {
+	return &Limiter{repo: repo, cfg: cfg}
+}
+
+// maxReserveTries bounds retries when concurrent attempts race for a counter
+const maxReserveTries = 5
+
+// Attempt is a login attempt that has been counted as a failure in
+// advance. Report its outcome with Failure, Success or Cancel.
+type Attempt struct {
+	limiter      *Limiter
+	username     string
+	reservations []reservation
+}
+
+type reservation struct {
+	key      limitKey
+	prev     *model.LoginFailure
+	reserved *model.LoginFailure
+}
+
+// Begin returns a *BlockedError while the username or client IP has to
+// wait. Otherwise it counts the attempt as a failure before the password
+// is checked, so concurrent guesses cannot all pass on the same counter;
+// only one attempt per username and IP is in flight at a time. Blocked
+// attempts are not counted, so waiting always helps.
+func (l *Limiter) Begin(ctx context.Context, ip, username string) (*Attempt, error) {
+	// Postgres keeps microseconds; reservations are compared exactly
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	keys := l.keys(ip, username)
+
+	// Report the longest wait, not just the first counter that blocks
+	var worst *BlockedError
+	for _, k := range keys {
+		failure, err := l.get(ctx, k.key)
+		if err != nil {
+			return nil, err
+		}
+		if failure == nil {
+			continue
+		}
+		if b := l.blockedFor(failure, k.limit, now); b != nil && (worst == nil || b.RetryAfter > worst.RetryAfter) {
+			worst = b
+		}
+	}
+	if worst != nil {
+		blocked.Inc()
+		return nil, worst
+	}
+
+	a := &Attempt{limiter: l, username: username}
+	for _, k := range keys {
+		r, err := l.reserve(ctx, k, now)
+		if err != nil {
+			a.refund(ctx, nil)
+			var b *BlockedError
+			if errors.As(err, &b) {
+				blocked.Inc()
+			}
+			return nil, err
+		}
+		a.reservations = append(a.reservations, *r)
+	}
+	return a, nil
+}
+
+// reserve counts a failure on the counter unless it blocks logins. A
+// counter changed by a concurrent attempt is read again.
+func (l *Limiter) reserve(ctx context.Context, k limitKey, now time.Time) (*reservation, error) {
+	for i := 0; i < maxReserveTries; i++ {
+		prev, err := l.get(ctx, k.key)
+		if err != nil {
+			return nil, err
+		}
+		next := &model.LoginFailure{Key: k.key, Failures: 1, LastFailure: now}
+		if prev != nil {
+			if b := l.blockedFor(prev, k.limit, now); b != nil {
+				return nil, b
+			}
+			// A counter whose last failure is older than the window starts over
+			if !prev.LastFailure.Before(now.Add(-l.cfg.Window)) {
+				next.Failures = prev.Failures + 1
+			}
+		}
+		ok, err := l.repo.Reserve(ctx, prev, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record login attempt: %w", err)
+		}
+		if ok {
+			return &reservation{key: k, prev: prev, reserved: next}, nil
+		}
+	}
+	// Still racing after several tries: treat it like backoff
+	return nil, &BlockedError{RetryAfter: l.cfg.BackoffBase}
+}
+
+func (l *Limiter) get(ctx context.Context, key string) (*model.LoginFailure, error) {
+	failure, err := l.repo.Get(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read login failures: %w", err)
+	}
+	return failure, nil
+}
+
+// Failure keeps the attempt counted as a failed login
+func (a *Attempt) Failure(ctx context.Context) {
+	failures.Inc()
+	for _, r := range a.reservations {
+		if r.key.limit > 0 && r.reserved.Failures >= r.key.limit {
+			lockouts.Inc()
+		}
+	}
+	a.limiter.prune(ctx, time.Now())
+}
+
+// Success clears the username's failures and takes the attempt back from
+// the IP counter. The IP's earlier failures are kept, so one valid account
+// does not reset guessing against others from the same IP.
+func (a *Attempt) Success(ctx context.Context) error {
+	if err := a.limiter.repo.Reset(ctx, userKey(a.username)); err != nil {
+		return err
+	}
+	return a.refund(ctx, func(r reservation) bool { return r.key.key != userKey(a.username) })
+}
+
+// Cancel takes the attempt back when the password could not be checked
+func (a *Attempt) Cancel(ctx context.Context) error {
+	return a.refund(ctx, nil)
+}
+
+// refund takes back the reservations that only returns true for (nil: all)
+func (a *Attempt) refund(ctx context.Context, only func(reservation) bool) error {
+	var first error
+	for _, r := range a.reservations {
+		if only != nil && !only(r) {
+			continue
+		}
+		if err := a.limiter.repo.Refund(ctx, r.reserved, r.prev); err != nil && first == nil {
+			first = fmt.Errorf("failed to refund login attempt: %w", err)
+		}
+	}
+	return first
+}
+
+type limitKey struct {
+	key   string
+	limit int
+}
+
+func (l *Limiter) keys(ip, username string) []limitKey {
+	keys := []limitKey{{key: userKey(username), limit: l.cfg.MaxFailuresPerUser}}
+	if ip != "" {
+		keys = append(keys, limitKey{key: "ip:" + ip, limit: l.cfg.MaxFailuresPerIP})
+	}
+	return keys
+}
+
+// userKey ignores case and surrounding spaces, which Casdoor ignores too
+func userKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// blockedFor returns how long the counter still blocks logins, if at all
+func (l *Limiter) blockedFor(failure *model.LoginFailure, limit int, now time.Time) *BlockedError {
+	locked := limit > 0 && failure.Failures >= limit
+	wait := l.cfg.Lockout
+	if !locked {
+		wait = l.backoff(failure.Failures)
+	}
+	if remaining := failure.LastFailure.Add(wait).Sub(now); remaining > 0 {
+		return &BlockedError{RetryAfter: remaining, Locked: locked}
+	}
+	return nil
+}
+
+// backoff is BackoffBase doubled for every failure after the first,
+// capped at BackoffMax
+func (l *Limiter) backoff(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	d := float64(l.cfg.BackoffBase) * math.Pow(2, float64(n-1))
+	if d > float64(l.cfg.BackoffMax) {
+		return l.cfg.BackoffMax
+	}
+	return time.Duration(d)
+}
+
+// prune drops expired counters at most once per window
+func (l *Limiter) prune(ctx context.Context, now time.Time) {
+	last := l.lastPrune.Load()
+	if now.Sub(time.Unix(0, last)) < l.cfg.Window || !l.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	// Keep counters that still lock someone out
+	cutoff := now.Add(-l.cfg.Window)
+	if lock := now.Add(-l.cfg.Lockout); lock.Before(cutoff) {
+		cutoff = lock
+	}
+	if _, err := l.repo.Prune(ctx, cutoff); err != nil {
+		log.Printf("Warning: failed to prune login failures: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package metrics keeps process metrics and serves them in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+type metric interface {
+	write(b *strings.Builder)
+}
+
+var (
+	registry     = map[string]metric{}
+	registryLock sync.RWMutex
+)
+
+func register(name string, m metric) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	registry[name] = m
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	name, help string
+	value      atomic.Uint64
+}
+
+// NewCounter registers a counter
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(name, c)
+	return c
+}
+
+// Inc adds one
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) write(b *strings.Builder) {
+	writeMetric(b, c.name, c.help, "counter", float64(c.value.Load()))
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct {
+	name, help string
+	bits       atomic.Uint64
+}
+
+// NewGauge registers a gauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(name, g)
+	return g
+}
+
+// Set sets the value
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) write(b *strings.Builder) {
+	writeMetric(b, g.name, g.help, "gauge", g.Value())
+}
+
+// GaugeFunc is a gauge computed when scraped
+type GaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn()
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	register(name, g)
+	return g
+}
+
+func (g *GaugeFunc) write(b *strings.Builder) {
+	writeMetric(b, g.name, g.help, "gauge", g.fn())
+}
+
+func writeMetric(b *strings.Builder, name, help, kind string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+// Handler serves every registered metric
+// GET /metrics
+func Handler(c echo.Context) error {
+	registryLock.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		registry[name].write(&b)
+	}
+	registryLock.RUnlock()
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
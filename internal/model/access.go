@@ -0,0 +1,8 @@
+package model
+
+// Access tells the caller how they reached a record they do not own
+// outright. It is filled in per request and never stored.
+type Access struct {
+	Via  string `json:"via"`  // owner, shared, delegated, policy or admin
+	Role string `json:"role"` // owner, editor or viewer
+}
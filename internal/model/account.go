@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// DefaultCurrency is the currency of accounts and transactions
+const DefaultCurrency = "USD"
+
+// Account kinds. User accounts may never go below zero; system accounts
+// represent money entering or leaving the ledger and may.
+const (
+	AccountKindUser   = "user"
+	AccountKindSystem = "system"
+)
+
+// System account owners. The external account funds deposits and receives
+// withdrawals; the merchant account receives order payments and pays refunds.
+const (
+	ExternalAccountOwner = "system:external"
+	MerchantAccountOwner = "system:merchant"
+)
+
+// Account holds a balance in a single currency
+type Account struct {
+	ID        string      `json:"id" gorm:"primaryKey"`
+	Owner     string      `json:"owner" gorm:"uniqueIndex:idx_accounts_owner_currency"`
+	Currency  string      `json:"currency" gorm:"size:3;uniqueIndex:idx_accounts_owner_currency"`
+	Kind      string      `json:"kind"`
+	Balance   money.Money `json:"balance" gorm:"embedded;embeddedPrefix:balance_"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Entry directions. A credit increases an account balance, a debit decreases it.
+const (
+	EntryDebit  = "debit"
+	EntryCredit = "credit"
+)
+
+// LedgerEntry is one side of a posted transaction. The entries of a
+// transaction always balance: total debits equal total credits.
+type LedgerEntry struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	TransactionID string      `json:"transaction_id" gorm:"index"`
+	AccountID     string      `json:"account_id" gorm:"index"`
+	Direction     string      `json:"direction"`
+	Amount        money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, which tells keys apart from JWTs
+const APIKeyPrefix = "ak_"
+
+// APIKey is a personal API key. Only the SHA-256 hash of the key is
+// stored; the key itself is shown once when it is created. The owner's
+// profile is copied at creation; admin status is looked up on use, and
+// Scopes ("object:action") limit the owner's Casbin permissions.
+type APIKey struct {
+	ID     string   `json:"id" gorm:"primaryKey"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"` // Start of the key, to recognize it
+	Hash   string   `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes []string `json:"scopes" gorm:"serializer:json"`
+
+	UserName    string `json:"user_name" gorm:"index;not null"` // Casbin subject of the owner
+	UserID      string `json:"user_id"`
+	Owner       string `json:"-"`
+	DisplayName string `json:"-"`
+	Email       string `json:"-"`
+
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HashAPIKey returns the stored form of an API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the key has expired at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
@@ -0,0 +1,8 @@
+package model
+
+import "github.com/google/uuid"
+
+// NewID returns a random identifier with a resource prefix, e.g. "ord_<uuid>"
+func NewID(prefix string) string {
+	return prefix + "_" + uuid.NewString()
+}
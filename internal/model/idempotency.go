@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// IdempotencyRecord stores the first response for an Idempotency-Key so
+// retries of the same request can be replayed
+type IdempotencyRecord struct {
+	UserID       string `gorm:"primaryKey"`
+	Key          string `gorm:"primaryKey"`
+	RequestHash  string `gorm:"not null"`
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+}
+
+// Completed reports whether a response has been stored for the key
+func (r *IdempotencyRecord) Completed() bool {
+	return r.CompletedAt != nil
+}
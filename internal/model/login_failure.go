@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginFailure counts recent failed logins for a username or client IP.
+// Key is "user:<name>" or "ip:<address>".
+type LoginFailure struct {
+	Key         string    `json:"key" gorm:"primaryKey"`
+	Failures    int       `json:"failures" gorm:"not null"`
+	LastFailure time.Time `json:"last_failure" gorm:"index"`
+}
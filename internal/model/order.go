@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// Order is a customer order persisted in the orders table
+type Order struct {
+	ID              string      `json:"id" gorm:"primaryKey"`
+	UserID          string      `json:"user_id" gorm:"index"`
+	SKU             string      `json:"sku" gorm:"index"`
+	ProductName     string      `json:"product_name"` // Snapshot of the catalog name when ordered
+	Quantity        int         `json:"quantity"`
+	Price           money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Total           money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	Status          string      `json:"status" gorm:"index"`
+	CreatedAt       time.Time   `json:"created_at" gorm:"index"`
+	CreatedBy       string      `json:"created_by"`
+	PaymentID       string      `json:"payment_transaction_id,omitempty"`                     // Refunds link back via Transaction.OrderID
+	ShippingAddress string      `json:"shipping_address,omitempty" gorm:"serializer:transit"` // Customer details; encrypted when field encryption is on
+	Access          *Access     `json:"access,omitempty" gorm:"-"`
+}
+
+// Order lifecycle statuses
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusReturned   = "returned"
+)
+
+// orderTransitions lists the statuses an order may move to from each status
+var orderTransitions = map[string][]string{
+	OrderStatusPending:    {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:  {OrderStatusReturned},
+	OrderStatusCancelled:  {},
+	OrderStatusReturned:   {},
+}
+
+// IsValidOrderStatus reports whether status is part of the order lifecycle
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedOrderTransitions returns the statuses reachable from status
+func AllowedOrderTransitions(status string) []string {
+	return append([]string{}, orderTransitions[status]...)
+}
+
+// OrderStatusHistory records a single order status change
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    string    `json:"order_id" gorm:"index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
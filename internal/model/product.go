@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// Product is a catalog entry persisted in the products table. Orders take
+// their price from the catalog and reserve Stock when they are placed.
+type Product struct {
+	ID          string      `json:"id" gorm:"primaryKey"`
+	SKU         string      `json:"sku" gorm:"uniqueIndex"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Price       money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Stock       int         `json:"stock"`
+	Active      bool        `json:"active" gorm:"index"` // Inactive products cannot be ordered
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// ServiceAccountPrefix starts the Casbin subject of every service account
+const ServiceAccountPrefix = "svc:"
+
+// ServiceAccount lets a machine client authenticate with the OAuth
+// client_credentials grant. ClientID is the Casdoor application whose
+// tokens act as the account; its policies are those of Subject().
+type ServiceAccount struct {
+	Name        string    `json:"name" gorm:"primaryKey"`
+	ClientID    string    `json:"client_id" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description,omitempty"`
+	Disabled    bool      `json:"disabled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Subject is the Casbin subject of the account, e.g. "svc:billing"
+func (a *ServiceAccount) Subject() string {
+	return ServiceAccountPrefix + a.Name
+}
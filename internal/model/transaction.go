@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// Transaction types. Amounts are always positive; the type decides which
+// accounts are debited and credited. Payments and refunds are created by
+// the order flow and always reference an order.
+const (
+	TransactionTypeDeposit    = "deposit"
+	TransactionTypeWithdrawal = "withdrawal"
+	TransactionTypeTransfer   = "transfer"
+	TransactionTypePayment    = "payment"
+	TransactionTypeRefund     = "refund"
+)
+
+// Transaction statuses. Transactions above the approval threshold wait in
+// pending_approval until a second user approves (completed) or rejects them.
+const (
+	TransactionStatusPending         = "pending"
+	TransactionStatusPendingApproval = "pending_approval"
+	TransactionStatusCompleted       = "completed"
+	TransactionStatusRejected        = "rejected"
+)
+
+// IsValidTransactionType reports whether t is a supported transaction type
+func IsValidTransactionType(t string) bool {
+	switch t {
+	case TransactionTypeDeposit, TransactionTypeWithdrawal, TransactionTypeTransfer,
+		TransactionTypePayment, TransactionTypeRefund:
+		return true
+	}
+	return false
+}
+
+// IsOrderTransactionType reports whether t is created by the order flow
+// rather than directly through the transactions API
+func IsOrderTransactionType(t string) bool {
+	return t == TransactionTypePayment || t == TransactionTypeRefund
+}
+
+// Transaction is a financial transaction persisted in the transactions table
+type Transaction struct {
+	ID           string      `json:"id" gorm:"primaryKey"`
+	UserID       string      `json:"user_id" gorm:"index"`
+	AccountID    string      `json:"account_id" gorm:"index"`
+	Amount       money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Type         string      `json:"type" gorm:"index"`
+	Status       string      `json:"status" gorm:"index"`
+	Description  string      `json:"description" gorm:"serializer:transit"` // Encrypted when field encryption is on
+	Counterparty string      `json:"counterparty,omitempty"`                // Receiving user of a transfer
+	OrderID      string      `json:"order_id,omitempty" gorm:"index"`
+	CreatedAt    time.Time   `json:"created_at" gorm:"index"`
+	CreatedBy    string      `json:"created_by"`
+	ReviewedBy   string      `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time  `json:"reviewed_at,omitempty"`
+	ReviewNote   string      `json:"review_note,omitempty"`
+	Access       *Access     `json:"access,omitempty" gorm:"-"`
+}
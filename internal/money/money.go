@@ -0,0 +1,213 @@
+// Package money implements exact monetary amounts in ISO-4217 currencies.
+//
+// Amounts are held as an integer number of minor units (cents for USD,
+// yen for JPY) so arithmetic never loses precision. In the database a
+// Money is stored as two columns, <prefix>minor and <prefix>currency; in
+// JSON it is {"amount": "12.34", "currency": "USD"} with the amount as a
+// decimal string.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+// exponents maps supported ISO-4217 codes to their number of minor unit digits
+var exponents = map[string]int{
+	"AUD": 2, "CAD": 2, "CHF": 2, "CNY": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"INR": 2, "NZD": 2, "SGD": 2, "THB": 2, "USD": 2,
+	"JPY": 0, "KRW": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3,
+}
+
+// Exponent returns the number of minor unit digits of currency
+func Exponent(currency string) (int, error) {
+	exp, ok := exponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return exp, nil
+}
+
+// ValidCurrency reports whether currency is a supported ISO-4217 code
+func ValidCurrency(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
+
+// Money is an exact amount in a single currency
+type Money struct {
+	Minor    int64  `gorm:"column:minor;not null;default:0"`
+	Currency string `gorm:"column:currency;size:3"`
+}
+
+// New returns an amount of minor units in currency
+func New(minor int64, currency string) (Money, error) {
+	if !ValidCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Zero returns a zero amount in currency
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// Parse reads a decimal string such as "12.34" or "-0.5" in currency.
+// Digits beyond the currency's minor unit are rounded half to even
+// (banker's rounding), so "0.125" USD is 0.12 and "0.135" USD is 0.14.
+func Parse(s, currency string) (Money, error) {
+	exp, err := Exponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || s == "" || strings.ContainsAny(s, "eE/") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	scaled := r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)))
+	minor := roundHalfEven(scaled)
+	if !minor.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: minor.Int64(), Currency: currency}, nil
+}
+
+// roundHalfEven rounds r to the nearest integer, ties to even
+func roundHalfEven(r *big.Rat) *big.Int {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Compare 2*|rem| with the denominator to find the nearest integer
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	cmp := twice.Cmp(r.Denom())
+	if cmp > 0 || (cmp == 0 && quo.Bit(0) == 1) {
+		if r.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
+
+// String formats the amount as a decimal without the currency, e.g. "12.34"
+func (m Money) String() string {
+	exp := exponents[m.Currency]
+	if exp == 0 {
+		return fmt.Sprintf("%d", m.Minor)
+	}
+
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+	}
+	// Work on the unsigned magnitude so math.MinInt64 formats correctly
+	magnitude := new(big.Int).Abs(big.NewInt(minor)).String()
+	if len(magnitude) <= exp {
+		magnitude = strings.Repeat("0", exp-len(magnitude)+1) + magnitude
+	}
+	return sign + magnitude[:len(magnitude)-exp] + "." + magnitude[len(magnitude)-exp:]
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// Add returns m + other. Adding different currencies is an error.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	sum := m.Minor + other.Minor
+	if (other.Minor > 0 && sum < m.Minor) || (other.Minor < 0 && sum > m.Minor) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Subtracting different currencies is an error.
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Minor == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(other.Neg())
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Mul returns m multiplied by an integer quantity
+func (m Money) Mul(quantity int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.Minor), big.NewInt(quantity))
+	if !product.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Minor: product.Int64(), Currency: m.Currency}, nil
+}
+
+// Cmp compares m with other: -1 if m < other, 0 if equal, +1 if m > other.
+// Comparing different currencies is an error.
+func (m Money) Cmp(other Money) (int, error) {
+	if m.Currency != other.Currency {
+		return 0, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	switch {
+	case m.Minor < other.Minor:
+		return -1, nil
+	case m.Minor > other.Minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes m as {"amount": "12.34", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.String(), Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes {"amount": "12.34", "currency": "USD"}
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%w: expected {\"amount\": \"...\", \"currency\": \"...\"}", ErrInvalidAmount)
+	}
+	parsed, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package mtls serves TLS with a server certificate issued by OpenBao PKI
+// and maps verified client certificates to Casbin subjects, so internal
+// services can call the API without a user's bearer token.
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"casdoor-casbin-openbao/internal/config"
+	"casdoor-casbin-openbao/internal/metrics"
+	"casdoor-casbin-openbao/internal/openbao"
+)
+
+const (
+	// ClientAuthOptional accepts connections without a client certificate;
+	// those callers authenticate with bearer tokens
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects connections without a valid certificate
+	ClientAuthRequire = "require"
+
+	// renewRetry is the wait after a failed renewal
+	renewRetry = 30 * time.Second
+)
+
+var (
+	certExpiry = metrics.NewGaugeFunc("tls_server_certificate_expiry_seconds",
+		"Seconds until the server certificate expires", func() float64 {
+			if s := current.Load(); s != nil {
+				return s.remaining().Seconds()
+			}
+			return 0
+		})
+	renewals = metrics.NewCounter("tls_server_certificate_renewals_total",
+		"Server certificate renewals")
+	renewalFailures = metrics.NewCounter("tls_server_certificate_renewal_failures_total",
+		"Failed server certificate renewals")
+)
+
+var current atomic.Pointer[Server]
+
+// serviceName is the part of a certificate name that becomes the subject
+var serviceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Server keeps the server certificate and the trusted client CAs current.
+// Handshakes always use the latest certificate, so renewals need no
+// restart.
+type Server struct {
+	pki        *openbao.PKI
+	cfg        config.MTLSConfig
+	clientAuth tls.ClientAuthType
+
+	state atomic.Pointer[certState]
+}
+
+type certState struct {
+	cert    tls.Certificate
+	cas     *x509.CertPool
+	expires time.Time
+	issued  time.Time
+}
+
+// NewServer issues the first server certificate from the PKI role
+func NewServer(ctx context.Context, pki *openbao.PKI, cfg config.MTLSConfig) (*Server, error) {
+	s := &Server{pki: pki, cfg: cfg}
+	switch cfg.ClientAuth {
+	case ClientAuthOptional, "":
+		s.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		s.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown MTLS_CLIENT_AUTH %q (use optional or require)", cfg.ClientAuth)
+	}
+	if err := s.renew(ctx); err != nil {
+		return nil, err
+	}
+	current.Store(s)
+	return s, nil
+}
+
+// TLSConfig returns the configuration for the TLS listener
+func (s *Server) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			st := s.state.Load()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{st.cert},
+				ClientCAs:    st.cas,
+				ClientAuth:   s.clientAuth,
+			}, nil
+		},
+	}
+}
+
+// Run renews the certificate at two thirds of its lifetime until ctx ends
+func (s *Server) Run(ctx context.Context) {
+	for {
+		wait := s.next()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if err := s.renew(ctx); err != nil {
+			renewalFailures.Inc()
+			log.Printf("Warning: server certificate renewal failed (expires in %s): %v", s.remaining().Round(time.Second), err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(renewRetry):
+			}
+			continue
+		}
+		renewals.Inc()
+		log.Printf("Server certificate renewed (expires %s)", s.state.Load().expires.Format(time.RFC3339))
+	}
+}
+
+func (s *Server) next() time.Duration {
+	st := s.state.Load()
+	wait := time.Until(st.issued.Add(st.expires.Sub(st.issued) * 2 / 3))
+	if wait < renewRetry {
+		wait = renewRetry
+	}
+	return wait
+}
+
+func (s *Server) remaining() time.Duration {
+	if d := time.Until(s.state.Load().expires); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// renew issues a new certificate and reloads the trusted CAs
+func (s *Server) renew(ctx context.Context) error {
+	var ips, dns []string
+	for _, name := range s.cfg.AltNames {
+		if net.ParseIP(name) != nil {
+			ips = append(ips, name)
+		} else {
+			dns = append(dns, name)
+		}
+	}
+	issued, err := s.pki.Issue(ctx, s.cfg.ServerRole, openbao.CertificateRequest{
+		CommonName: s.cfg.ServerName,
+		AltNames:   dns,
+		IPSANs:     ips,
+		TTL:        s.cfg.CertTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to issue server certificate: %w", err)
+	}
+
+	// Serve intermediates with the certificate; clients already have the root
+	chain := issued.Certificate
+	for _, ca := range issued.CAChain {
+		if !isRoot(ca) {
+			chain += "\n" + ca
+		}
+	}
+	cert, err := tls.X509KeyPair([]byte(chain), []byte(issued.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("invalid server certificate: %w", err)
+	}
+
+	cas := x509.NewCertPool()
+	ca, err := s.pki.CA(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the PKI CA: %w", err)
+	}
+	for _, c := range append([]string{ca, issued.IssuingCA}, issued.CAChain...) {
+		cas.AppendCertsFromPEM([]byte(c))
+	}
+
+	s.state.Store(&certState{cert: cert, cas: cas, expires: issued.Expiration, issued: time.Now()})
+	return nil
+}
+
+// Subject returns the Casbin subject of a verified client certificate:
+// SubjectPrefix followed by the service name. The name comes from the
+// common name or a DNS SAN under ServiceDomain, e.g. billing.svc.internal
+// → svc:billing. Connections without a verified certificate return false.
+func Subject(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cfg := config.GetConfig().MTLS
+	cert := state.VerifiedChains[0][0]
+
+	for _, name := range append([]string{cert.Subject.CommonName}, cert.DNSNames...) {
+		name = strings.ToLower(name)
+		if cfg.ServiceDomain != "" {
+			var ok bool
+			if name, ok = strings.CutSuffix(name, "."+strings.ToLower(cfg.ServiceDomain)); !ok {
+				continue
+			}
+		}
+		if serviceName.MatchString(name) {
+			return cfg.SubjectPrefix + name, true
+		}
+	}
+	return "", false
+}
+
+// isRoot reports whether a PEM certificate is self-signed
+func isRoot(certPEM string) bool {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer)
+}
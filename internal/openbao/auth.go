@@ -0,0 +1,143 @@
+package openbao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// authResponse is the auth block returned by login and token endpoints
+type authResponse struct {
+	ClientToken   string   `json:"client_token"`
+	Accessor      string   `json:"accessor"`
+	Policies      []string `json:"policies"`
+	LeaseDuration int      `json:"lease_duration"`
+	Renewable     bool     `json:"renewable"`
+}
+
+// Lease is the lifetime of a token or a secret. A zero Duration never
+// expires.
+type Lease struct {
+	ID        string
+	Duration  time.Duration
+	Renewable bool
+}
+
+// TokenInfo describes the client's token
+type TokenInfo struct {
+	Accessor string
+	Policies []string
+	Lease    Lease
+}
+
+func (a *authResponse) info() *TokenInfo {
+	return &TokenInfo{
+		Accessor: a.Accessor,
+		Policies: a.Policies,
+		Lease: Lease{
+			Duration:  time.Duration(a.LeaseDuration) * time.Second,
+			Renewable: a.Renewable,
+		},
+	}
+}
+
+// LoginAppRole logs in with the AppRole auth method mounted at mount and
+// uses the issued token for later requests
+func (c *Client) LoginAppRole(ctx context.Context, mount, roleID, secretID string) (*TokenInfo, error) {
+	if mount == "" {
+		mount = "approle"
+	}
+	res, err := c.do(ctx, http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", map[string]string{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Auth == nil || res.Auth.ClientToken == "" {
+		return nil, errors.New("openbao: login returned no token")
+	}
+	c.SetToken(res.Auth.ClientToken)
+	return res.Auth.info(), nil
+}
+
+// LookupSelf returns the client's token, checking that it is valid
+func (c *Client) LookupSelf(ctx context.Context) (*TokenInfo, error) {
+	res, err := c.do(ctx, http.MethodGet, "auth/token/lookup-self", nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Accessor  string   `json:"accessor"`
+		Policies  []string `json:"policies"`
+		TTL       int      `json:"ttl"`
+		Renewable bool     `json:"renewable"`
+	}
+	if err := res.decode(&data); err != nil {
+		return nil, err
+	}
+	return &TokenInfo{
+		Accessor: data.Accessor,
+		Policies: data.Policies,
+		Lease:    Lease{Duration: time.Duration(data.TTL) * time.Second, Renewable: data.Renewable},
+	}, nil
+}
+
+// RenewSelf extends the client's token by increment (zero keeps the
+// token's default TTL)
+func (c *Client) RenewSelf(ctx context.Context, increment time.Duration) (*TokenInfo, error) {
+	res, err := c.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]int{
+		"increment": int(increment / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Auth == nil {
+		return nil, errors.New("openbao: renew returned no token")
+	}
+	return res.Auth.info(), nil
+}
+
+// RenewLease extends a secret lease by increment and returns the new lease
+func (c *Client) RenewLease(ctx context.Context, leaseID string, increment time.Duration) (*Lease, error) {
+	res, err := c.do(ctx, http.MethodPut, "sys/leases/renew", map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+	lease := res.lease()
+	return &lease, nil
+}
+
+// RevokeLease revokes a secret lease, e.g. dropping the database role of
+// dynamic credentials
+func (c *Client) RevokeLease(ctx context.Context, leaseID string) error {
+	_, err := c.do(ctx, http.MethodPut, "sys/leases/revoke", map[string]interface{}{
+		"lease_id": leaseID,
+	})
+	return err
+}
+
+// RawSecret is the response of a logical read
+type RawSecret struct {
+	Data  map[string]interface{}
+	Lease Lease
+}
+
+// Read reads any logical path, e.g. "secret/data/app" or
+// "database/creds/app"
+func (c *Client) Read(ctx context.Context, path string) (*RawSecret, error) {
+	res, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := res.decode(&data); err != nil {
+		return nil, err
+	}
+	return &RawSecret{Data: data, Lease: res.lease()}, nil
+}
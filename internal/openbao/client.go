@@ -0,0 +1,168 @@
+// Package openbao is a small client for the OpenBao HTTP API.
+//
+// Only the endpoints the service uses are implemented. Package
+// openbaotest serves the same endpoints in-process for tests.
+package openbao
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for paths that do not exist
+	ErrNotFound = errors.New("openbao: not found")
+	// ErrPermissionDenied is returned when the token may not use a path
+	ErrPermissionDenied = errors.New("openbao: permission denied")
+	// ErrVersionConflict is returned by check-and-set writes when the
+	// secret changed since it was read
+	ErrVersionConflict = errors.New("openbao: check-and-set version mismatch")
+)
+
+// APIError is a non-2xx response other than 403 and 404
+type APIError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("openbao: status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Config configures a Client
+type Config struct {
+	// Address is the server URL, e.g. "http://127.0.0.1:8200"
+	Address   string
+	Token     string
+	Namespace string
+	Timeout   time.Duration
+}
+
+// Client calls the OpenBao HTTP API with one token
+type Client struct {
+	address    string
+	namespace  string
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// NewClient creates a client
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("openbao: address is required")
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		address:    strings.TrimRight(cfg.Address, "/"),
+		namespace:  cfg.Namespace,
+		token:      cfg.Token,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Token returns the current token
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the token used for later requests
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// response is the envelope of OpenBao API responses
+type response struct {
+	Data          json.RawMessage `json:"data"`
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Auth          *authResponse   `json:"auth"`
+	Errors        []string        `json:"errors"`
+}
+
+// lease returns the lease of a response
+func (r *response) lease() Lease {
+	return Lease{
+		ID:        r.LeaseID,
+		Duration:  time.Duration(r.LeaseDuration) * time.Second,
+		Renewable: r.Renewable,
+	}
+}
+
+// do sends a request to /v1/<path>. body is JSON-encoded when not nil; the
+// response envelope is returned for 2xx responses.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+strings.TrimLeft(path, "/"), reader)
+	if err != nil {
+		return nil, err
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("openbao: %w", err)
+	}
+	defer res.Body.Close()
+
+	var out response
+	if res.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("openbao: invalid response: %w", err)
+		}
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case res.StatusCode == http.StatusForbidden:
+		return nil, ErrPermissionDenied
+	case res.StatusCode >= 300:
+		return nil, &APIError{StatusCode: res.StatusCode, Errors: out.Errors}
+	}
+	return &out, nil
+}
+
+// decode unmarshals the data field of a response into v
+func (r *response) decode(v interface{}) error {
+	if len(r.Data) == 0 || string(r.Data) == "null" {
+		return ErrNotFound
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return fmt.Errorf("openbao: invalid response data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package openbao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DatabaseCredentials are a username and password issued by the database
+// secrets engine. They stop working when Lease expires or is revoked.
+type DatabaseCredentials struct {
+	Username string
+	Password string
+	Lease    Lease
+}
+
+// DatabaseCredentials creates credentials for role of the database secrets
+// engine mounted at mount, e.g. "database"
+func (c *Client) DatabaseCredentials(ctx context.Context, mount, role string) (*DatabaseCredentials, error) {
+	res, err := c.do(ctx, http.MethodGet, strings.Trim(mount, "/")+"/creds/"+role, nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := res.decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Username == "" || res.LeaseID == "" {
+		return nil, errors.New("openbao: database credentials response has no username or lease")
+	}
+	return &DatabaseCredentials{Username: data.Username, Password: data.Password, Lease: res.lease()}, nil
+}
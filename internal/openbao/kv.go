@@ -0,0 +1,112 @@
+package openbao
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KV is a KV version 2 secrets engine mounted at mount
+type KV struct {
+	client *Client
+	mount  string
+}
+
+// KV returns the KV v2 engine mounted at mount, e.g. "secret"
+func (c *Client) KV(mount string) *KV {
+	return &KV{client: c, mount: strings.Trim(mount, "/")}
+}
+
+// Secret is one version of a KV secret
+type Secret struct {
+	Data     map[string]interface{} `json:"data"`
+	Metadata SecretMetadata         `json:"metadata"`
+}
+
+// SecretMetadata describes a version of a secret
+type SecretMetadata struct {
+	Version     int       `json:"version"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// Get reads the latest version of the secret at path
+func (k *KV) Get(ctx context.Context, path string) (*Secret, error) {
+	res, err := k.client.do(ctx, http.MethodGet, k.mount+"/data/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var secret Secret
+	if err := res.decode(&secret); err != nil {
+		return nil, err
+	}
+	// A deleted latest version reads as data: null
+	if secret.Data == nil {
+		return nil, ErrNotFound
+	}
+	return &secret, nil
+}
+
+// Put writes a new version of the secret at path
+func (k *KV) Put(ctx context.Context, path string, data map[string]interface{}) (*SecretMetadata, error) {
+	return k.put(ctx, path, map[string]interface{}{"data": data})
+}
+
+// PutCAS writes a new version of the secret at path only if its current
+// version is version (0: the secret must not exist yet). Otherwise it
+// returns ErrVersionConflict.
+func (k *KV) PutCAS(ctx context.Context, path string, data map[string]interface{}, version int) (*SecretMetadata, error) {
+	meta, err := k.put(ctx, path, map[string]interface{}{
+		"data":    data,
+		"options": map[string]int{"cas": version},
+	})
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(strings.Join(apiErr.Errors, " "), "check-and-set") {
+		return nil, ErrVersionConflict
+	}
+	return meta, err
+}
+
+func (k *KV) put(ctx context.Context, path string, body map[string]interface{}) (*SecretMetadata, error) {
+	res, err := k.client.do(ctx, http.MethodPost, k.mount+"/data/"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	var meta SecretMetadata
+	if err := res.decode(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Delete soft-deletes the latest version of the secret at path; earlier
+// versions stay recoverable in OpenBao
+func (k *KV) Delete(ctx context.Context, path string) error {
+	_, err := k.client.do(ctx, http.MethodDelete, k.mount+"/data/"+path, nil)
+	return err
+}
+
+// List returns the keys directly under path. Keys ending in "/" are
+// folders. An empty or missing folder returns ErrNotFound.
+func (k *KV) List(ctx context.Context, path string) ([]string, error) {
+	res, err := k.client.do(ctx, "LIST", k.mount+"/metadata/"+strings.TrimSuffix(path, "/")+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Keys []string `json:"keys"`
+	}
+	if err := res.decode(&list); err != nil {
+		return nil, err
+	}
+	return list.Keys, nil
+}
+
+// Destroy permanently removes every version and the metadata of the
+// secret at path
+func (k *KV) Destroy(ctx context.Context, path string) error {
+	_, err := k.client.do(ctx, http.MethodDelete, k.mount+"/metadata/"+path, nil)
+	return err
+}
@@ -0,0 +1,123 @@
+package openbao_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"casdoor-casbin-openbao/internal/openbao"
+	"casdoor-casbin-openbao/internal/openbao/openbaotest"
+)
+
+func newKV(t *testing.T) *openbao.KV {
+	t.Helper()
+	fake := openbaotest.NewFake("root")
+	t.Cleanup(fake.Close)
+	client, err := openbao.NewClient(openbao.Config{Address: fake.URL(), Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.KV("secret")
+}
+
+func TestKVReadWrite(t *testing.T) {
+	ctx := context.Background()
+	kv := newKV(t)
+
+	meta, err := kv.Put(ctx, "app/users/alice/db", map[string]interface{}{"password": "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Version != 1 {
+		t.Errorf("first version = %d, want 1", meta.Version)
+	}
+	if meta, err = kv.Put(ctx, "app/users/alice/db", map[string]interface{}{"password": "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Version != 2 {
+		t.Errorf("second version = %d, want 2", meta.Version)
+	}
+
+	secret, err := kv.Get(ctx, "app/users/alice/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["password"] != "two" || secret.Metadata.Version != 2 {
+		t.Errorf("Get = %v (version %d), want the second version", secret.Data, secret.Metadata.Version)
+	}
+
+	if _, err := kv.Get(ctx, "app/users/alice/missing"); !errors.Is(err, openbao.ErrNotFound) {
+		t.Errorf("Get of a missing secret: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestKVPutCAS(t *testing.T) {
+	ctx := context.Background()
+	kv := newKV(t)
+
+	if _, err := kv.PutCAS(ctx, "policy", map[string]interface{}{"v": "1"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.PutCAS(ctx, "policy", map[string]interface{}{"v": "2"}, 0); !errors.Is(err, openbao.ErrVersionConflict) {
+		t.Errorf("PutCAS on a stale version: err = %v, want ErrVersionConflict", err)
+	}
+	if _, err := kv.PutCAS(ctx, "policy", map[string]interface{}{"v": "2"}, 1); err != nil {
+		t.Errorf("PutCAS on the current version: %v", err)
+	}
+}
+
+func TestKVList(t *testing.T) {
+	ctx := context.Background()
+	kv := newKV(t)
+
+	for _, path := range []string{"app/users/alice/db", "app/users/alice/api/token", "app/users/bob/db"} {
+		if _, err := kv.Put(ctx, path, map[string]interface{}{"k": "v"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := kv.List(ctx, "app/users/alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api/", "db"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List = %v, want %v", keys, want)
+	}
+	// A trailing slash names the same folder
+	if keys, err = kv.List(ctx, "app/users/"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alice/", "bob/"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List = %v, want %v", keys, want)
+	}
+	if _, err := kv.List(ctx, "app/users/carol"); !errors.Is(err, openbao.ErrNotFound) {
+		t.Errorf("List of an empty folder: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestKVDelete(t *testing.T) {
+	ctx := context.Background()
+	kv := newKV(t)
+
+	if _, err := kv.Put(ctx, "app/users/alice/db", map[string]interface{}{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Delete(ctx, "app/users/alice/db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.Get(ctx, "app/users/alice/db"); !errors.Is(err, openbao.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	// A soft-deleted secret keeps its metadata, so it is still listed
+	if keys, err := kv.List(ctx, "app/users/alice"); err != nil || len(keys) != 1 {
+		t.Errorf("List after Delete = %v, %v; want the deleted key", keys, err)
+	}
+
+	if err := kv.Destroy(ctx, "app/users/alice/db"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.List(ctx, "app/users/alice"); !errors.Is(err, openbao.ErrNotFound) {
+		t.Errorf("List after Destroy: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,623 @@
+// Package openbaotest runs an in-process OpenBao server for tests. Only
+// test files import it, so it is never built into the server.
+package openbaotest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/openbao"
+)
+
+// Fake is an in-process stand-in for an OpenBao server. It keeps
+// everything in memory and implements only the endpoints package openbao
+// calls. Requests must carry RootToken or a token it issued; policies are
+// not evaluated and JWT signatures are not checked.
+type Fake struct {
+	RootToken string
+	// TokenTTL is the lifetime of tokens issued by AppRole logins
+	TokenTTL time.Duration
+
+	server *httptest.Server
+
+	mu       sync.Mutex
+	kv       map[string]map[string]*fakeSecret // mount → path → secret
+	appRoles map[string]string                 // role_id → secret_id
+	jwtRoles map[string]bool                   // "<mount>/<role>"
+	tokens   map[string]*fakeToken
+	dbRoles  map[string]fakeDBRole // "<mount>/creds/<role>" → role
+	leases   map[string]*fakeLease
+	transit  map[string]map[string][][]byte // mount → key name → versions
+	pki      map[string]*fakeCA
+	serial   int
+}
+
+type fakeDBRole struct {
+	ttl, maxTTL time.Duration
+}
+
+type fakeLease struct {
+	ttl        time.Duration
+	expires    time.Time
+	maxExpires time.Time
+}
+
+type fakeToken struct {
+	accessor string
+	policies []string
+	meta     map[string]string
+	parent   string // token that created it; empty for roots and orphans
+	ttl      time.Duration
+	expires  time.Time // zero for the root token
+}
+
+type fakeSecret struct {
+	versions []fakeVersion
+}
+
+type fakeVersion struct {
+	data    map[string]interface{}
+	created time.Time
+	deleted bool
+}
+
+// NewFake starts a fake server with a KV v2 engine mounted at "secret"
+func NewFake(rootToken string) *Fake {
+	f := &Fake{
+		RootToken: rootToken,
+		TokenTTL:  time.Hour,
+		kv:        map[string]map[string]*fakeSecret{"secret": {}},
+		appRoles:  map[string]string{},
+		jwtRoles:  map[string]bool{},
+		dbRoles:   map[string]fakeDBRole{},
+		leases:    map[string]*fakeLease{},
+		transit:   map[string]map[string][][]byte{},
+		pki:       map[string]*fakeCA{},
+		tokens: map[string]*fakeToken{
+			rootToken: {accessor: "root", policies: []string{"root"}},
+		},
+	}
+	f.server = httptest.NewServer(f)
+	return f
+}
+
+// URL is the address to give Config.Address
+func (f *Fake) URL() string {
+	return f.server.URL
+}
+
+// Close stops the server
+func (f *Fake) Close() {
+	f.server.Close()
+}
+
+// MountKV adds a KV v2 engine at mount
+func (f *Fake) MountKV(mount string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.kv[mount] == nil {
+		f.kv[mount] = map[string]*fakeSecret{}
+	}
+}
+
+// AddAppRole enables AppRole logins with roleID and secretID
+func (f *Fake) AddAppRole(roleID, secretID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appRoles[roleID] = secretID
+}
+
+// AddJWTRole enables JWT logins to role of the JWT auth method at mount.
+// The fake accepts any well-formed JWT; it does not verify signatures or
+// bound claims.
+func (f *Fake) AddJWTRole(mount, role string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jwtRoles[mount+"/"+role] = true
+}
+
+// MountDatabase adds a database secrets engine role at mount whose
+// credentials live for ttl, renewable up to maxTTL. The fake only issues
+// credentials; it does not create database users.
+func (f *Fake) MountDatabase(mount, role string, ttl, maxTTL time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dbRoles[mount+"/creds/"+role] = fakeDBRole{ttl: ttl, maxTTL: maxTTL}
+}
+
+// MountTransit adds a transit engine at mount. Keys are AES-256-GCM and
+// created on first encrypt.
+func (f *Fake) MountTransit(mount string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.transit[mount] == nil {
+		f.transit[mount] = map[string][][]byte{}
+	}
+}
+
+// ServeHTTP implements the fake API
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/")
+	if !ok {
+		fakeError(w, http.StatusNotFound)
+		return
+	}
+
+	method := r.Method
+	if method == http.MethodGet && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path == "auth/approle/login" && method == http.MethodPost {
+		f.serveAppRoleLogin(w, r)
+		return
+	}
+
+	if mount, ok := strings.CutSuffix(strings.TrimPrefix(path, "auth/"), "/login"); ok && method == http.MethodPost {
+		f.serveJWTLogin(w, r, mount)
+		return
+	}
+
+	id := r.Header.Get("X-Vault-Token")
+	token := f.tokens[id]
+	if token == nil || (!token.expires.IsZero() && time.Now().After(token.expires)) {
+		fakeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case path == "auth/token/lookup-self" && method == http.MethodGet:
+		fakeData(w, map[string]interface{}{
+			"accessor":  token.accessor,
+			"policies":  token.policies,
+			"ttl":       token.remaining(),
+			"renewable": !token.expires.IsZero(),
+		})
+		return
+	case path == "auth/token/renew-self" && (method == http.MethodPost || method == http.MethodPut):
+		if token.expires.IsZero() {
+			fakeError(w, http.StatusBadRequest, "lease is not renewable")
+			return
+		}
+		token.expires = time.Now().Add(token.ttl)
+		f.writeAuth(w, r.Header.Get("X-Vault-Token"), token)
+		return
+	case (path == "auth/token/create" || strings.HasPrefix(path, "auth/token/create/")) && (method == http.MethodPost || method == http.MethodPut):
+		f.serveTokenCreate(w, r, id, token)
+		return
+	case path == "auth/token/revoke-accessor" && (method == http.MethodPost || method == http.MethodPut):
+		f.serveRevokeAccessor(w, r)
+		return
+	case path == "sys/leases/renew" && (method == http.MethodPost || method == http.MethodPut):
+		f.serveLeaseRenew(w, r)
+		return
+	case path == "sys/leases/revoke" && (method == http.MethodPost || method == http.MethodPut):
+		f.serveLeaseRevoke(w, r)
+		return
+	}
+
+	if role, ok := f.dbRoles[path]; ok && method == http.MethodGet {
+		f.serveDatabaseCreds(w, path, role)
+		return
+	}
+
+	mount, rest, _ := strings.Cut(path, "/")
+	if secrets, ok := f.kv[mount]; ok {
+		f.serveKV(w, r, method, secrets, rest)
+		return
+	}
+	if keys, ok := f.transit[mount]; ok {
+		f.serveTransit(w, r, method, keys, rest)
+		return
+	}
+	if ca, ok := f.pki[mount]; ok {
+		f.servePKI(w, r, method, ca, rest)
+		return
+	}
+	fakeError(w, http.StatusNotFound, "no handler for route \""+path+"\"")
+}
+
+func (f *Fake) serveAppRoleLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RoleID   string `json:"role_id"`
+		SecretID string `json:"secret_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	secretID, ok := f.appRoles[body.RoleID]
+	if !ok || secretID != body.SecretID {
+		fakeError(w, http.StatusBadRequest, "invalid role or secret ID")
+		return
+	}
+
+	id, token := f.issue([]string{"default"}, nil, "", f.TokenTTL)
+	f.writeAuth(w, id, token)
+}
+
+func (f *Fake) serveJWTLogin(w http.ResponseWriter, r *http.Request, mount string) {
+	var body struct {
+		Role string `json:"role"`
+		JWT  string `json:"jwt"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	if !f.jwtRoles[mount+"/"+body.Role] {
+		fakeError(w, http.StatusBadRequest, "role \""+body.Role+"\" could not be found")
+		return
+	}
+	if strings.Count(body.JWT, ".") != 2 {
+		fakeError(w, http.StatusBadRequest, "error validating token: malformed JWT")
+		return
+	}
+	id, token := f.issue([]string{"default"}, nil, "", f.TokenTTL)
+	f.writeAuth(w, id, token)
+}
+
+// serveTokenCreate creates a child token, or an orphan when a token role
+// is named. Requested policies are granted as asked.
+func (f *Fake) serveTokenCreate(w http.ResponseWriter, r *http.Request, parentID string, parent *fakeToken) {
+	var body struct {
+		Policies []string          `json:"policies"`
+		Meta     map[string]string `json:"meta"`
+		TTL      string            `json:"ttl"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	ttl := f.TokenTTL
+	if body.TTL != "" {
+		d, err := time.ParseDuration(body.TTL)
+		if err != nil || d <= 0 {
+			fakeError(w, http.StatusBadRequest, "invalid ttl")
+			return
+		}
+		ttl = d
+	}
+	if strings.HasPrefix(r.URL.Path, "/v1/auth/token/create/") {
+		parentID = ""
+	} else if !parent.expires.IsZero() && time.Now().Add(ttl).After(parent.expires) {
+		ttl = time.Until(parent.expires).Round(time.Second)
+	}
+	policies := body.Policies
+	if len(policies) == 0 {
+		policies = parent.policies
+	}
+	id, token := f.issue(policies, body.Meta, parentID, ttl)
+	f.writeAuth(w, id, token)
+}
+
+func (f *Fake) serveRevokeAccessor(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Accessor string `json:"accessor"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	for id, token := range f.tokens {
+		if token.accessor == body.Accessor {
+			f.revoke(id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	fakeError(w, http.StatusBadRequest, "invalid accessor")
+}
+
+// revoke removes a token and its children
+func (f *Fake) revoke(id string) {
+	delete(f.tokens, id)
+	for child, token := range f.tokens {
+		if token.parent == id {
+			f.revoke(child)
+		}
+	}
+}
+
+// issue stores a new token
+func (f *Fake) issue(policies []string, meta map[string]string, parent string, ttl time.Duration) (string, *fakeToken) {
+	f.serial++
+	id := fmt.Sprintf("fake-token-%d", f.serial)
+	token := &fakeToken{
+		accessor: fmt.Sprintf("fake-accessor-%d", f.serial),
+		policies: policies,
+		meta:     meta,
+		parent:   parent,
+		ttl:      ttl,
+		expires:  time.Now().Add(ttl),
+	}
+	f.tokens[id] = token
+	return id, token
+}
+
+func (f *Fake) serveDatabaseCreds(w http.ResponseWriter, path string, role fakeDBRole) {
+	f.serial++
+	id := fmt.Sprintf("%s/fake-lease-%d", path, f.serial)
+	now := time.Now()
+	f.leases[id] = &fakeLease{ttl: role.ttl, expires: now.Add(role.ttl), maxExpires: now.Add(role.maxTTL)}
+	fakeJSON(w, http.StatusOK, map[string]interface{}{
+		"lease_id":       id,
+		"lease_duration": int(role.ttl / time.Second),
+		"renewable":      true,
+		"data": map[string]interface{}{
+			"username": fmt.Sprintf("v-fake-%d", f.serial),
+			"password": fmt.Sprintf("fake-password-%d", f.serial),
+		},
+	})
+}
+
+func (f *Fake) serveLeaseRenew(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		LeaseID   string `json:"lease_id"`
+		Increment int    `json:"increment"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	lease := f.leases[body.LeaseID]
+	now := time.Now()
+	if lease == nil || now.After(lease.expires) {
+		fakeError(w, http.StatusBadRequest, "lease not found or lease is not renewable")
+		return
+	}
+
+	increment := time.Duration(body.Increment) * time.Second
+	if increment == 0 {
+		increment = lease.ttl
+	}
+	lease.expires = now.Add(increment)
+	if lease.expires.After(lease.maxExpires) {
+		lease.expires = lease.maxExpires
+	}
+	fakeJSON(w, http.StatusOK, map[string]interface{}{
+		"lease_id":       body.LeaseID,
+		"lease_duration": int(time.Until(lease.expires).Round(time.Second) / time.Second),
+		"renewable":      true,
+	})
+}
+
+func (f *Fake) serveLeaseRevoke(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		LeaseID string `json:"lease_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	delete(f.leases, body.LeaseID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Fake) writeAuth(w http.ResponseWriter, id string, token *fakeToken) {
+	fakeJSON(w, http.StatusOK, map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token":   id,
+			"accessor":       token.accessor,
+			"policies":       token.policies,
+			"lease_duration": token.remaining(),
+			"renewable":      !token.expires.IsZero(),
+		},
+	})
+}
+
+// remaining is the token's TTL in seconds; 0 never expires
+func (t *fakeToken) remaining() int {
+	if t.expires.IsZero() {
+		return 0
+	}
+	return int(time.Until(t.expires).Round(time.Second) / time.Second)
+}
+
+func (f *Fake) serveKV(w http.ResponseWriter, r *http.Request, method string, secrets map[string]*fakeSecret, path string) {
+	kind, key, _ := strings.Cut(path, "/")
+	switch {
+	case kind == "data" && method == http.MethodGet:
+		secret := secrets[key]
+		if secret == nil || secret.latest().deleted {
+			fakeError(w, http.StatusNotFound)
+			return
+		}
+		v := secret.latest()
+		fakeData(w, map[string]interface{}{
+			"data":     v.data,
+			"metadata": map[string]interface{}{"version": len(secret.versions), "created_time": v.created},
+		})
+
+	case kind == "data" && (method == http.MethodPost || method == http.MethodPut):
+		var body struct {
+			Data    map[string]interface{} `json:"data"`
+			Options struct {
+				CAS *int `json:"cas"`
+			} `json:"options"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Data == nil {
+			fakeError(w, http.StatusBadRequest, "no data provided")
+			return
+		}
+		secret := secrets[key]
+		if cas := body.Options.CAS; cas != nil {
+			current := 0
+			if secret != nil {
+				current = len(secret.versions)
+			}
+			if *cas != current {
+				fakeError(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+				return
+			}
+		}
+		if secret == nil {
+			secret = &fakeSecret{}
+			secrets[key] = secret
+		}
+		now := time.Now().UTC()
+		secret.versions = append(secret.versions, fakeVersion{data: body.Data, created: now})
+		fakeData(w, map[string]interface{}{"version": len(secret.versions), "created_time": now})
+
+	case kind == "data" && method == http.MethodDelete:
+		if secret := secrets[key]; secret != nil {
+			secret.versions[len(secret.versions)-1].deleted = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case kind == "metadata" && method == http.MethodDelete:
+		delete(secrets, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	case kind == "metadata" && method == "LIST":
+		prefix := strings.TrimSuffix(key, "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		seen := map[string]bool{}
+		for name := range secrets {
+			rest, ok := strings.CutPrefix(name, prefix)
+			if !ok {
+				continue
+			}
+			if dir, _, isDir := strings.Cut(rest, "/"); isDir {
+				seen[dir+"/"] = true
+			} else {
+				seen[rest] = true
+			}
+		}
+		if len(seen) == 0 {
+			fakeError(w, http.StatusNotFound)
+			return
+		}
+		keys := make([]string, 0, len(seen))
+		for k := range seen {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fakeData(w, map[string]interface{}{"keys": keys})
+
+	default:
+		fakeError(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+func (f *Fake) serveTransit(w http.ResponseWriter, r *http.Request, method string, keys map[string][][]byte, path string) {
+	var body struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	op, name, _ := strings.Cut(path, "/")
+	name, rotate := strings.CutSuffix(name, "/rotate")
+	versions := keys[name]
+
+	switch {
+	case op == "encrypt" && method == http.MethodPost:
+		plaintext, err := base64.StdEncoding.DecodeString(body.Plaintext)
+		if err != nil {
+			fakeError(w, http.StatusBadRequest, "plaintext is not base64")
+			return
+		}
+		if versions == nil {
+			versions = [][]byte{fakeKey()}
+			keys[name] = versions
+		}
+		fakeData(w, map[string]interface{}{"ciphertext": fakeSeal(versions, len(versions), plaintext)})
+
+	case (op == "decrypt" || op == "rewrap") && method == http.MethodPost:
+		plaintext, err := fakeOpen(versions, body.Ciphertext)
+		if err != nil {
+			fakeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if op == "decrypt" {
+			fakeData(w, map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+			return
+		}
+		fakeData(w, map[string]interface{}{"ciphertext": fakeSeal(versions, len(versions), plaintext)})
+
+	case op == "keys" && rotate && method == http.MethodPost:
+		if versions == nil {
+			fakeError(w, http.StatusBadRequest, "key not found")
+			return
+		}
+		keys[name] = append(versions, fakeKey())
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "keys" && method == http.MethodPost:
+		if versions == nil {
+			keys[name] = [][]byte{fakeKey()}
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case op == "keys" && method == http.MethodGet:
+		if versions == nil {
+			fakeError(w, http.StatusNotFound)
+			return
+		}
+		fakeData(w, map[string]interface{}{"name": name, "latest_version": len(versions), "min_decryption_version": 1})
+
+	default:
+		fakeError(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+func fakeKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+func fakeGCM(key []byte) cipher.AEAD {
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	return gcm
+}
+
+// fakeSeal encrypts with key version n (1-based) in the transit format
+func fakeSeal(versions [][]byte, n int, plaintext []byte) string {
+	gcm := fakeGCM(versions[n-1])
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return fmt.Sprintf("%s%d:%s", openbao.CiphertextPrefix, n, base64.StdEncoding.EncodeToString(sealed))
+}
+
+func fakeOpen(versions [][]byte, ciphertext string) ([]byte, error) {
+	n := openbao.CiphertextVersion(ciphertext)
+	if n == 0 || n > len(versions) {
+		return nil, errors.New("invalid ciphertext or key version")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext[strings.LastIndex(ciphertext, ":")+1:])
+	gcm := fakeGCM(versions[n-1])
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("cipher: message authentication failed")
+	}
+	return plaintext, nil
+}
+
+func (s *fakeSecret) latest() fakeVersion {
+	return s.versions[len(s.versions)-1]
+}
+
+func fakeData(w http.ResponseWriter, data interface{}) {
+	fakeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func fakeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func fakeError(w http.ResponseWriter, status int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+	fakeJSON(w, status, map[string]interface{}{"errors": errs})
+}
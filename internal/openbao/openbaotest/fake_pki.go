@@ -0,0 +1,144 @@
+package openbaotest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// fakeCA is the root CA of a fake PKI engine
+type fakeCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+// MountPKI adds a PKI engine at mount with a new self-signed root CA. Every
+// role name issues certificates valid for both server and client auth.
+func (f *Fake) MountPKI(mount string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake OpenBao root CA (" + mount + ")"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pki[mount] = &fakeCA{
+		cert: cert,
+		key:  key,
+		pem:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+	return nil
+}
+
+func (f *Fake) servePKI(w http.ResponseWriter, r *http.Request, method string, ca *fakeCA, path string) {
+	switch {
+	case path == "cert/ca" && method == http.MethodGet:
+		fakeData(w, map[string]interface{}{"certificate": ca.pem})
+	case strings.HasPrefix(path, "issue/") && (method == http.MethodPost || method == http.MethodPut):
+		f.serveIssue(w, r, ca)
+	default:
+		fakeError(w, http.StatusNotFound)
+	}
+}
+
+func (f *Fake) serveIssue(w http.ResponseWriter, r *http.Request, ca *fakeCA) {
+	var body struct {
+		CommonName string `json:"common_name"`
+		AltNames   string `json:"alt_names"`
+		IPSANs     string `json:"ip_sans"`
+		TTL        string `json:"ttl"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	if body.CommonName == "" {
+		fakeError(w, http.StatusBadRequest, "the common_name field is required")
+		return
+	}
+	ttl := 24 * time.Hour
+	if body.TTL != "" {
+		d, err := time.ParseDuration(body.TTL)
+		if err != nil || d <= 0 {
+			fakeError(w, http.StatusBadRequest, "invalid ttl")
+			return
+		}
+		ttl = d
+	}
+
+	dnsNames := []string{body.CommonName}
+	for _, name := range strings.Split(body.AltNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+	var ips []net.IP
+	for _, s := range strings.Split(body.IPSANs, ",") {
+		if ip := net.ParseIP(strings.TrimSpace(s)); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	f.serial++
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(f.serial) + 1),
+		Subject:      pkix.Name{CommonName: body.CommonName},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		fakeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fakeData(w, map[string]interface{}{
+		"certificate":   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		"private_key":   string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		"issuing_ca":    ca.pem,
+		"ca_chain":      []string{ca.pem},
+		"serial_number": fmt.Sprintf("%x", template.SerialNumber),
+		"expiration":    template.NotAfter.Unix(),
+	})
+}
@@ -0,0 +1,67 @@
+package openbao
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RefScheme prefixes secret references in configuration values
+const RefScheme = "bao://"
+
+// Ref points at one key of a secret: bao://<path>#<key>, e.g.
+// bao://secret/data/app#db_password. path is the API path read with a
+// GET, so KV v2 paths include "data/".
+type Ref struct {
+	Path string
+	Key  string
+}
+
+// IsRef reports whether a configuration value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefScheme)
+}
+
+// ParseRef parses a bao:// reference
+func ParseRef(value string) (Ref, error) {
+	rest, ok := strings.CutPrefix(value, RefScheme)
+	if !ok {
+		return Ref{}, fmt.Errorf("openbao: %q is not a %s reference", value, RefScheme)
+	}
+	path, key, ok := strings.Cut(rest, "#")
+	path = strings.Trim(path, "/")
+	if !ok || path == "" || key == "" {
+		return Ref{}, fmt.Errorf("openbao: reference %q must look like %s<path>#<key>", value, RefScheme)
+	}
+	return Ref{Path: path, Key: key}, nil
+}
+
+func (r Ref) String() string {
+	return RefScheme + r.Path + "#" + r.Key
+}
+
+// Resolve reads the referenced value and the lease it was issued under.
+// KV v2 responses are unwrapped, so bao://secret/data/app#key reads the
+// key of the latest version.
+func (c *Client) Resolve(ctx context.Context, ref Ref) (string, Lease, error) {
+	secret, err := c.Read(ctx, ref.Path)
+	if err != nil {
+		return "", Lease{}, fmt.Errorf("%s: %w", ref, err)
+	}
+
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+
+	value, ok := data[ref.Key]
+	if !ok || value == nil {
+		return "", Lease{}, fmt.Errorf("%s: key %q not found", ref, ref.Key)
+	}
+	if s, ok := value.(string); ok {
+		return s, secret.Lease, nil
+	}
+	return fmt.Sprint(value), secret.Lease, nil
+}
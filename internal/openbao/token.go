@@ -0,0 +1,103 @@
+package openbao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TokenRequest describes a token to create
+type TokenRequest struct {
+	// Role is a token role (auth/token/roles/<role>) that bounds the
+	// policies and TTL; empty creates a child of the client's token
+	Role        string
+	Policies    []string
+	TTL         time.Duration
+	DisplayName string
+	Meta        map[string]string
+	Renewable   bool
+}
+
+// IssuedToken is a token created by CreateToken or a login
+type IssuedToken struct {
+	Token    string
+	Accessor string
+	Policies []string
+	Lease    Lease
+}
+
+func (a *authResponse) issued() *IssuedToken {
+	return &IssuedToken{
+		Token:    a.ClientToken,
+		Accessor: a.Accessor,
+		Policies: a.Policies,
+		Lease:    a.info().Lease,
+	}
+}
+
+// WithToken returns a client for the same server that uses token
+func (c *Client) WithToken(token string) *Client {
+	return &Client{
+		address:    c.address,
+		namespace:  c.namespace,
+		httpClient: c.httpClient,
+		token:      token,
+	}
+}
+
+// CreateToken creates a token with the client's token
+func (c *Client) CreateToken(ctx context.Context, req TokenRequest) (*IssuedToken, error) {
+	path := "auth/token/create"
+	if req.Role != "" {
+		path += "/" + req.Role
+	}
+	body := map[string]interface{}{
+		"policies":     req.Policies,
+		"display_name": req.DisplayName,
+		"meta":         req.Meta,
+		"renewable":    req.Renewable,
+	}
+	if req.TTL > 0 {
+		body["ttl"] = fmt.Sprintf("%ds", int(req.TTL/time.Second))
+	}
+
+	res, err := c.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if res.Auth == nil || res.Auth.ClientToken == "" {
+		return nil, errors.New("openbao: token create returned no token")
+	}
+	return res.Auth.issued(), nil
+}
+
+// LoginJWT logs in with the JWT/OIDC auth method mounted at mount. Unlike
+// LoginAppRole it leaves the client's token alone and returns the issued
+// one.
+func (c *Client) LoginJWT(ctx context.Context, mount, role, jwt string) (*IssuedToken, error) {
+	if mount == "" {
+		mount = "jwt"
+	}
+	res, err := c.WithToken("").do(ctx, http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", map[string]string{
+		"role": role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Auth == nil || res.Auth.ClientToken == "" {
+		return nil, errors.New("openbao: login returned no token")
+	}
+	return res.Auth.issued(), nil
+}
+
+// RevokeAccessor revokes the token with accessor and all its children
+func (c *Client) RevokeAccessor(ctx context.Context, accessor string) error {
+	_, err := c.do(ctx, http.MethodPost, "auth/token/revoke-accessor", map[string]string{
+		"accessor": accessor,
+	})
+	return err
+}
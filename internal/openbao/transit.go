@@ -0,0 +1,133 @@
+package openbao
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CiphertextPrefix starts every transit ciphertext, followed by the key
+// version: "vault:v3:..."
+const CiphertextPrefix = "vault:v"
+
+// Transit is a transit secrets engine mounted at mount
+type Transit struct {
+	client *Client
+	mount  string
+}
+
+// Transit returns the transit engine mounted at mount, e.g. "transit"
+func (c *Client) Transit(mount string) *Transit {
+	return &Transit{client: c, mount: strings.Trim(mount, "/")}
+}
+
+// TransitKey describes a named encryption key
+type TransitKey struct {
+	Name                 string `json:"name"`
+	LatestVersion        int    `json:"latest_version"`
+	MinDecryptionVersion int    `json:"min_decryption_version"`
+}
+
+// CiphertextVersion returns the key version a ciphertext was encrypted
+// with, or 0 when s is not a transit ciphertext
+func CiphertextVersion(s string) int {
+	rest, ok := strings.CutPrefix(s, CiphertextPrefix)
+	if !ok {
+		return 0
+	}
+	version, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Encrypt encrypts plaintext with the latest version of key. The key is
+// created on first use when the token's policy allows it.
+func (t *Transit) Encrypt(ctx context.Context, key, plaintext string) (string, error) {
+	res, err := t.client.do(ctx, http.MethodPost, t.mount+"/encrypt/"+key, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return ciphertextOf(res)
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt or Rewrap
+func (t *Transit) Decrypt(ctx context.Context, key, ciphertext string) (string, error) {
+	res, err := t.client.do(ctx, http.MethodPost, t.mount+"/decrypt/"+key, map[string]string{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return "", err
+	}
+	var data struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := res.decode(&data); err != nil {
+		return "", err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(data.Plaintext)
+	if err != nil {
+		return "", fmt.Errorf("openbao: invalid plaintext: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-encrypts a ciphertext with the latest key version without
+// revealing the plaintext
+func (t *Transit) Rewrap(ctx context.Context, key, ciphertext string) (string, error) {
+	res, err := t.client.do(ctx, http.MethodPost, t.mount+"/rewrap/"+key, map[string]string{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return "", err
+	}
+	return ciphertextOf(res)
+}
+
+// CreateKey creates key if it does not exist yet
+func (t *Transit) CreateKey(ctx context.Context, key string) error {
+	_, err := t.client.do(ctx, http.MethodPost, t.mount+"/keys/"+key, nil)
+	return err
+}
+
+// RotateKey adds a new version of key; new encryptions use it
+func (t *Transit) RotateKey(ctx context.Context, key string) error {
+	_, err := t.client.do(ctx, http.MethodPost, t.mount+"/keys/"+key+"/rotate", nil)
+	return err
+}
+
+// Key returns the versions of key
+func (t *Transit) Key(ctx context.Context, key string) (*TransitKey, error) {
+	res, err := t.client.do(ctx, http.MethodGet, t.mount+"/keys/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	var info TransitKey
+	if err := res.decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func ciphertextOf(res *response) (string, error) {
+	var data struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := res.decode(&data); err != nil {
+		return "", err
+	}
+	if CiphertextVersion(data.Ciphertext) == 0 {
+		return "", fmt.Errorf("openbao: invalid ciphertext in response")
+	}
+	return data.Ciphertext, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// ErrInsufficientFunds is returned when posting would overdraw a user account
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// AccountRepository stores accounts and their ledger entries
+type AccountRepository interface {
+	// GetOrCreate returns the account of owner in currency, creating it if needed
+	GetOrCreate(ctx context.Context, owner, kind, currency string) (*model.Account, error)
+	Get(ctx context.Context, id string) (*model.Account, error)
+	// Post applies balanced entries to their accounts atomically. Accounts
+	// are locked while balances are checked and updated; a user account
+	// that would go below zero fails with ErrInsufficientFunds.
+	Post(ctx context.Context, entries []model.LedgerEntry) error
+	ListEntries(ctx context.Context, accountID string) ([]model.LedgerEntry, error)
+}
+
+// validateEntries checks that entries are well-formed and balance per currency
+func validateEntries(entries []model.LedgerEntry) error {
+	if len(entries) < 2 {
+		return fmt.Errorf("a posting needs at least two entries")
+	}
+
+	net := map[string]int64{}
+	for _, entry := range entries {
+		if !entry.Amount.IsPositive() {
+			return fmt.Errorf("entry amount must be positive")
+		}
+		switch entry.Direction {
+		case model.EntryDebit:
+			net[entry.Amount.Currency] -= entry.Amount.Minor
+		case model.EntryCredit:
+			net[entry.Amount.Currency] += entry.Amount.Minor
+		default:
+			return fmt.Errorf("unknown entry direction %q", entry.Direction)
+		}
+	}
+
+	for currency, diff := range net {
+		if diff != 0 {
+			return fmt.Errorf("entries are not balanced in %s", currency)
+		}
+	}
+	return nil
+}
+
+// applyEntries returns the new balance of each account after posting
+// entries. Entries in a currency other than the account's are rejected.
+func applyEntries(accounts map[string]model.Account, entries []model.LedgerEntry) (map[string]money.Money, error) {
+	balances := map[string]money.Money{}
+	for _, entry := range entries {
+		account, ok := accounts[entry.AccountID]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		balance, ok := balances[entry.AccountID]
+		if !ok {
+			balance = account.Balance
+		}
+
+		amount := entry.Amount
+		if entry.Direction == model.EntryDebit {
+			amount = amount.Neg()
+		}
+		next, err := balance.Add(amount)
+		if err != nil {
+			return nil, err
+		}
+		balances[entry.AccountID] = next
+	}
+
+	for id, balance := range balances {
+		if accounts[id].Kind == model.AccountKindUser && balance.IsNegative() {
+			return nil, ErrInsufficientFunds
+		}
+	}
+	return balances, nil
+}
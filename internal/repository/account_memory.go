@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+type memoryAccountRepository struct {
+	mu       sync.Mutex
+	accounts map[string]*model.Account
+	entries  []model.LedgerEntry
+}
+
+// NewMemoryAccountRepository creates an in-memory AccountRepository for tests
+func NewMemoryAccountRepository() AccountRepository {
+	return &memoryAccountRepository{accounts: map[string]*model.Account{}}
+}
+
+func (r *memoryAccountRepository) GetOrCreate(ctx context.Context, owner, kind, currency string) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, account := range r.accounts {
+		if account.Owner == owner && account.Currency == currency {
+			result := *account
+			return &result, nil
+		}
+	}
+
+	now := time.Now()
+	account := &model.Account{
+		ID:        model.NewID("acc"),
+		Owner:     owner,
+		Currency:  currency,
+		Kind:      kind,
+		Balance:   money.Zero(currency),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.accounts[account.ID] = account
+	result := *account
+	return &result, nil
+}
+
+func (r *memoryAccountRepository) Get(ctx context.Context, id string) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *account
+	return &result, nil
+}
+
+func (r *memoryAccountRepository) Post(ctx context.Context, entries []model.LedgerEntry) error {
+	if err := validateEntries(entries); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	accounts := map[string]model.Account{}
+	for _, entry := range entries {
+		if account, ok := r.accounts[entry.AccountID]; ok {
+			accounts[entry.AccountID] = *account
+		}
+	}
+	balances, err := applyEntries(accounts, entries)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for id, balance := range balances {
+		r.accounts[id].Balance = balance
+		r.accounts[id].UpdatedAt = now
+	}
+	for _, entry := range entries {
+		entry.ID = uint(len(r.entries) + 1)
+		entry.CreatedAt = now
+		r.entries = append(r.entries, entry)
+	}
+	return nil
+}
+
+func (r *memoryAccountRepository) ListEntries(ctx context.Context, accountID string) ([]model.LedgerEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []model.LedgerEntry{}
+	for _, entry := range r.entries {
+		if entry.AccountID == accountID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresAccountRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresAccountRepository creates an AccountRepository backed by Postgres
+func NewPostgresAccountRepository(db *gorm.DB) AccountRepository {
+	return &postgresAccountRepository{db: db}
+}
+
+func (r *postgresAccountRepository) GetOrCreate(ctx context.Context, owner, kind, currency string) (*model.Account, error) {
+	account := model.Account{
+		ID:       model.NewID("acc"),
+		Owner:    owner,
+		Currency: currency,
+		Kind:     kind,
+		Balance:  money.Zero(currency),
+	}
+	if err := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+
+	var existing model.Account
+	if err := conn(ctx, r.db).First(&existing, "owner = ? AND currency = ?", owner, currency).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *postgresAccountRepository) Get(ctx context.Context, id string) (*model.Account, error) {
+	var account model.Account
+	if err := conn(ctx, r.db).First(&account, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *postgresAccountRepository) Post(ctx context.Context, entries []model.LedgerEntry) error {
+	if err := validateEntries(entries); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, entry := range entries {
+		if !seen[entry.AccountID] {
+			seen[entry.AccountID] = true
+			ids = append(ids, entry.AccountID)
+		}
+	}
+	// Lock in a stable order so concurrent postings cannot deadlock
+	sort.Strings(ids)
+
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var locked []model.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).Order("id").Find(&locked).Error; err != nil {
+			return err
+		}
+		accounts := map[string]model.Account{}
+		for _, account := range locked {
+			accounts[account.ID] = account
+		}
+
+		balances, err := applyEntries(accounts, entries)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for id, balance := range balances {
+			if err := tx.Model(&model.Account{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"balance_minor": balance.Minor, "updated_at": now}).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range entries {
+			entries[i].CreatedAt = now
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
+func (r *postgresAccountRepository) ListEntries(ctx context.Context, accountID string) ([]model.LedgerEntry, error) {
+	entries := []model.LedgerEntry{}
+	if err := conn(ctx, r.db).Where("account_id = ?", accountID).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// APIKeyRepository stores API keys by hash
+type APIKeyRepository interface {
+	// ListByUser returns the keys of a user, newest first
+	ListByUser(ctx context.Context, userName string) ([]model.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	Create(ctx context.Context, key *model.APIKey) error
+	// Touch records that the key was used at
+	Touch(ctx context.Context, id string, at time.Time) error
+	// Delete removes a key of userName; keys of other users are ErrNotFound
+	Delete(ctx context.Context, userName, id string) error
+}
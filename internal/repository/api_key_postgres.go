@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+)
+
+type postgresAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresAPIKeyRepository creates an APIKeyRepository backed by Postgres
+func NewPostgresAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &postgresAPIKeyRepository{db: db}
+}
+
+func (r *postgresAPIKeyRepository) ListByUser(ctx context.Context, userName string) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	if err := conn(ctx, r.db).Where("user_name = ?", userName).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *postgresAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := conn(ctx, r.db).First(&key, "hash = ?", hash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *postgresAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	return conn(ctx, r.db).Create(key).Error
+}
+
+func (r *postgresAPIKeyRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	return conn(ctx, r.db).Model(&model.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *postgresAPIKeyRepository) Delete(ctx context.Context, userName, id string) error {
+	result := conn(ctx, r.db).Delete(&model.APIKey{}, "id = ? AND user_name = ?", id, userName)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// IdempotencyRepository stores Idempotency-Key records per user
+type IdempotencyRepository interface {
+	// Begin claims a key by inserting rec. If the key is already claimed,
+	// the existing record is returned and rec is not stored.
+	Begin(ctx context.Context, rec *model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	// Complete stores the response for a claimed key
+	Complete(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error
+	// Release removes a key so the request can be retried
+	Release(ctx context.Context, userID, key string) error
+}
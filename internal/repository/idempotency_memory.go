@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+// NewMemoryIdempotencyRepository creates an in-memory IdempotencyRepository for tests
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{records: map[string]model.IdempotencyRecord{}}
+}
+
+func (r *memoryIdempotencyRepository) Begin(ctx context.Context, rec *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := rec.UserID + "/" + rec.Key
+	if existing, ok := r.records[id]; ok {
+		return &existing, nil
+	}
+	r.records[id] = *rec
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := userID + "/" + key
+	rec, ok := r.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	now := time.Now()
+	rec.StatusCode = statusCode
+	rec.ContentType = contentType
+	rec.ResponseBody = append([]byte(nil), body...)
+	rec.CompletedAt = &now
+	r.records[id] = rec
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, userID+"/"+key)
+	return nil
+}
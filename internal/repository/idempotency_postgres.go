@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresIdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresIdempotencyRepository creates an IdempotencyRepository backed by Postgres
+func NewPostgresIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &postgresIdempotencyRepository{db: db}
+}
+
+func (r *postgresIdempotencyRepository) Begin(ctx context.Context, rec *model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	result := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing model.IdempotencyRecord
+	if err := conn(ctx, r.db).First(&existing, "user_id = ? AND key = ?", rec.UserID, rec.Key).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *postgresIdempotencyRepository) Complete(ctx context.Context, userID, key string, statusCode int, contentType string, body []byte) error {
+	now := time.Now()
+	return conn(ctx, r.db).Model(&model.IdempotencyRecord{}).
+		Where("user_id = ? AND key = ?", userID, key).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": body,
+			"completed_at":  &now,
+		}).Error
+}
+
+func (r *postgresIdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	return conn(ctx, r.db).Delete(&model.IdempotencyRecord{}, "user_id = ? AND key = ?", userID, key).Error
+}
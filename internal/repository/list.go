@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// ErrInvalidListOptions is returned when sort, cursor or filter values are invalid
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+// Filter narrows list results. Zero values mean "no filter".
+// The same grammar is shared by orders and transactions; Amount filters
+// apply to the order total and the transaction amount respectively and
+// must be in the filtered Currency.
+type Filter struct {
+	UserID    string
+	Status    string
+	Type      string
+	Currency  string
+	From      *time.Time
+	To        *time.Time
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	// Scope, when set, keeps only the rows the caller may read
+	Scope *Scope
+}
+
+// Scope limits results to rows matching at least one rule. A Scope
+// without rules keeps nothing; a nil Scope keeps everything.
+type Scope struct {
+	Rules []ScopeRule
+}
+
+// ScopeRule keeps rows owned by one of UserIDs or whose id is in IDs (when
+// either is set) and whose status is in Statuses (when set). An empty
+// rule keeps every row.
+type ScopeRule struct {
+	UserIDs  []string
+	IDs      []string
+	Statuses []string
+}
+
+// Matches reports whether a row passes the scope
+func (s *Scope) Matches(userID, id, status string) bool {
+	for _, r := range s.Rules {
+		owned := len(r.UserIDs) == 0 && len(r.IDs) == 0 || contains(r.UserIDs, userID) || contains(r.IDs, id)
+		if owned && (len(r.Statuses) == 0 || contains(r.Statuses, status)) {
+			return true
+		}
+	}
+	return false
+}
+
+// condition renders the scope as one SQL condition. ok is false when a
+// rule keeps every row, so no condition is needed.
+func (s *Scope) condition() (sql string, args []interface{}, ok bool) {
+	var clauses []string
+	for _, r := range s.Rules {
+		var conds []string
+		switch {
+		case len(r.UserIDs) > 0 && len(r.IDs) > 0:
+			conds = append(conds, "(user_id IN ? OR id IN ?)")
+			args = append(args, r.UserIDs, r.IDs)
+		case len(r.UserIDs) > 0:
+			conds = append(conds, "user_id IN ?")
+			args = append(args, r.UserIDs)
+		case len(r.IDs) > 0:
+			conds = append(conds, "id IN ?")
+			args = append(args, r.IDs)
+		}
+		if len(r.Statuses) > 0 {
+			conds = append(conds, "status IN ?")
+			args = append(args, r.Statuses)
+		}
+		if len(conds) == 0 {
+			return "", nil, false
+		}
+		clauses = append(clauses, "("+strings.Join(conds, " AND ")+")")
+	}
+	if len(clauses) == 0 {
+		return "1 = 0", nil, true
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args, true
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ListOptions controls pagination, sorting and filtering of list queries
+type ListOptions struct {
+	Limit  int
+	Cursor string
+	// Sort is a field name, prefixed with "-" for descending order
+	Sort   string
+	Filter Filter
+}
+
+// Page is one page of list results
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Sortable fields shared by all list endpoints
+const (
+	SortCreatedAt = "created_at"
+	SortAmount    = "amount"
+)
+
+// cursor identifies the last row of a page for keyset pagination
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+	}
+	return &c, nil
+}
+
+// listSchema describes how a resource maps onto the shared list grammar
+type listSchema[T any] struct {
+	resource     string
+	amountPrefix string
+	hasType      bool
+
+	id        func(T) string
+	userID    func(T) string
+	status    func(T) string
+	typ       func(T) string
+	createdAt func(T) time.Time
+	amount    func(T) money.Money
+}
+
+// normalized is a validated form of ListOptions
+type normalized struct {
+	limit  int
+	field  string
+	desc   bool
+	after  *cursor
+	filter Filter
+}
+
+func (s listSchema[T]) normalize(opts ListOptions) (*normalized, error) {
+	n := &normalized{limit: opts.Limit, filter: opts.Filter}
+	if n.limit <= 0 {
+		n.limit = DefaultListLimit
+	}
+	if n.limit > MaxListLimit {
+		n.limit = MaxListLimit
+	}
+
+	sortBy := opts.Sort
+	if sortBy == "" {
+		sortBy = "-" + SortCreatedAt
+	}
+	n.field = strings.TrimPrefix(sortBy, "-")
+	n.desc = strings.HasPrefix(sortBy, "-")
+	if n.field != SortCreatedAt && n.field != SortAmount {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListOptions, n.field)
+	}
+
+	if opts.Filter.Type != "" && !s.hasType {
+		return nil, fmt.Errorf("%w: %s cannot be filtered by type", ErrInvalidListOptions, s.resource)
+	}
+
+	for _, bound := range []*money.Money{opts.Filter.MinAmount, opts.Filter.MaxAmount} {
+		if bound == nil {
+			continue
+		}
+		if n.filter.Currency == "" {
+			n.filter.Currency = bound.Currency
+		}
+		if bound.Currency != n.filter.Currency {
+			return nil, fmt.Errorf("%w: amount filters must use currency %s", ErrInvalidListOptions, n.filter.Currency)
+		}
+	}
+
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortBy {
+			return nil, fmt.Errorf("%w: cursor does not match sort %q", ErrInvalidListOptions, sortBy)
+		}
+		if _, err := s.parseSortValue(n.field, c.Value); err != nil {
+			return nil, err
+		}
+		n.after = c
+	}
+
+	return n, nil
+}
+
+func (s listSchema[T]) sortColumn(field string) string {
+	if field == SortAmount {
+		return s.amountPrefix + "minor"
+	}
+	return "created_at"
+}
+
+func (s listSchema[T]) sortValue(field string, item T) string {
+	if field == SortAmount {
+		return strconv.FormatInt(s.amount(item).Minor, 10)
+	}
+	return s.createdAt(item).UTC().Format(time.RFC3339Nano)
+}
+
+func (s listSchema[T]) parseSortValue(field, value string) (interface{}, error) {
+	if field == SortAmount {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+		}
+		return v, nil
+	}
+	v, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidListOptions)
+	}
+	return v, nil
+}
+
+// page trims a result set fetched with limit+1 rows and sets the next cursor
+func (s listSchema[T]) page(n *normalized, items []T) *Page[T] {
+	result := &Page[T]{Items: items}
+	if len(items) > n.limit {
+		result.Items = items[:n.limit]
+		last := result.Items[len(result.Items)-1]
+		sortBy := n.field
+		if n.desc {
+			sortBy = "-" + sortBy
+		}
+		result.NextCursor = encodeCursor(cursor{Sort: sortBy, Value: s.sortValue(n.field, last), ID: s.id(last)})
+	}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	return result
+}
+
+// where applies filter conditions to a GORM query
+func (s listSchema[T]) where(db *gorm.DB, f Filter) *gorm.DB {
+	if f.UserID != "" {
+		db = db.Where("user_id = ?", f.UserID)
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.Type != "" {
+		db = db.Where("type = ?", f.Type)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+	if f.Currency != "" {
+		db = db.Where(s.amountPrefix+"currency = ?", f.Currency)
+	}
+	if f.MinAmount != nil {
+		db = db.Where(s.amountPrefix+"minor >= ?", f.MinAmount.Minor)
+	}
+	if f.MaxAmount != nil {
+		db = db.Where(s.amountPrefix+"minor <= ?", f.MaxAmount.Minor)
+	}
+	if f.Scope != nil {
+		if sql, args, ok := f.Scope.condition(); ok {
+			db = db.Where(sql, args...)
+		}
+	}
+	return db
+}
+
+// query applies filters, ordering and the keyset condition to a GORM query
+func (s listSchema[T]) query(db *gorm.DB, n *normalized) *gorm.DB {
+	db = s.where(db, n.filter)
+
+	column := s.sortColumn(n.field)
+	direction, op := "ASC", ">"
+	if n.desc {
+		direction, op = "DESC", "<"
+	}
+
+	if n.after != nil {
+		value, _ := s.parseSortValue(n.field, n.after.Value)
+		db = db.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", column, op, column, op),
+			value, value, n.after.ID,
+		)
+	}
+
+	return db.Order(column + " " + direction).Order("id " + direction).Limit(n.limit + 1)
+}
+
+// filter applies the same rules as query to an in-memory slice
+func (s listSchema[T]) filter(items []T, n *normalized) []T {
+	var result []T
+	for _, item := range items {
+		if s.matches(item, n.filter) {
+			result = append(result, item)
+		}
+	}
+
+	less := func(a, b T) bool {
+		if c := s.compare(n.field, a, b); c != 0 {
+			return c < 0
+		}
+		return s.id(a) < s.id(b)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if n.desc {
+			return less(result[j], result[i])
+		}
+		return less(result[i], result[j])
+	})
+
+	if n.after != nil {
+		for i, item := range result {
+			if s.isAfter(n, item) {
+				result = result[i:]
+				break
+			}
+			if i == len(result)-1 {
+				result = nil
+			}
+		}
+	}
+
+	if len(result) > n.limit+1 {
+		result = result[:n.limit+1]
+	}
+	return result
+}
+
+// matches reports whether item passes the same conditions as where
+func (s listSchema[T]) matches(item T, f Filter) bool {
+	switch {
+	case f.UserID != "" && s.userID(item) != f.UserID,
+		f.Status != "" && s.status(item) != f.Status,
+		f.Type != "" && s.typ(item) != f.Type,
+		f.From != nil && s.createdAt(item).Before(*f.From),
+		f.To != nil && !s.createdAt(item).Before(*f.To),
+		f.Currency != "" && s.amount(item).Currency != f.Currency,
+		f.MinAmount != nil && s.amount(item).Minor < f.MinAmount.Minor,
+		f.MaxAmount != nil && s.amount(item).Minor > f.MaxAmount.Minor,
+		f.Scope != nil && !f.Scope.Matches(s.userID(item), s.id(item), s.status(item)):
+		return false
+	}
+	return true
+}
+
+func (s listSchema[T]) compare(field string, a, b T) int {
+	if field == SortAmount {
+		switch x, y := s.amount(a).Minor, s.amount(b).Minor; {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return s.createdAt(a).Compare(s.createdAt(b))
+}
+
+// isAfter reports whether item comes after the cursor position
+func (s listSchema[T]) isAfter(n *normalized, item T) bool {
+	value, _ := s.parseSortValue(n.field, n.after.Value)
+
+	var c int
+	if n.field == SortAmount {
+		switch x, y := s.amount(item).Minor, value.(int64); {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	} else {
+		c = s.createdAt(item).Compare(value.(time.Time))
+	}
+	if c == 0 {
+		c = strings.Compare(s.id(item), n.after.ID)
+	}
+
+	if n.desc {
+		return c < 0
+	}
+	return c > 0
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "casdoor-casbin-openbao/internal/fieldcrypt" // transit serializer of the models
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens an empty SQLite database with tables for models
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testTransactions has ties on created_at and amount so the id tiebreak
+// of the keyset pagination is exercised
+func testTransactions() []model.Transaction {
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	users := []string{"alice", "bob", "carol"}
+	types := []string{model.TransactionTypeDeposit, model.TransactionTypeWithdrawal, model.TransactionTypeTransfer, model.TransactionTypePayment}
+	statuses := []string{model.TransactionStatusCompleted, model.TransactionStatusPending, model.TransactionStatusPendingApproval}
+
+	var txns []model.Transaction
+	for i := 0; i < 24; i++ {
+		currency := "USD"
+		if i%5 == 0 {
+			currency = "EUR"
+		}
+		txns = append(txns, model.Transaction{
+			ID:        fmt.Sprintf("txn_%02d", (i*7)%24),
+			UserID:    users[i%len(users)],
+			Type:      types[i%len(types)],
+			Status:    statuses[i%len(statuses)],
+			Amount:    money.Money{Minor: int64(1000 * (i % 6)), Currency: currency},
+			CreatedAt: base.Add(time.Duration(i/3) * 24 * time.Hour),
+		})
+	}
+	return txns
+}
+
+// newTransactionRepositories returns the Postgres implementation on SQLite
+// and the in-memory implementation, holding the same transactions
+func newTransactionRepositories(t *testing.T) map[string]TransactionRepository {
+	t.Helper()
+	txns := testTransactions()
+	db := newTestDB(t, &model.Transaction{})
+	if err := db.Create(&txns).Error; err != nil {
+		t.Fatal(err)
+	}
+	return map[string]TransactionRepository{
+		"postgres": NewPostgresTransactionRepository(db),
+		"memory":   NewMemoryTransactionRepository(testTransactions()...),
+	}
+}
+
+// listAll follows the cursors from the first page to the last and returns
+// the ids in order
+func listAll(t *testing.T, repo TransactionRepository, opts ListOptions) []string {
+	t.Helper()
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 50 {
+			t.Fatal("pagination does not end")
+		}
+		page, err := repo.List(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, txn := range page.Items {
+			ids = append(ids, txn.ID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func TestListImplementationsAgree(t *testing.T) {
+	repos := newTransactionRepositories(t)
+	if ids := listAll(t, repos["postgres"], ListOptions{}); len(ids) != 24 {
+		t.Fatalf("listed %d transactions, want 24", len(ids))
+	}
+	from := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)
+	minAmount := money.Money{Minor: 2000, Currency: "USD"}
+	maxAmount := money.Money{Minor: 4000, Currency: "USD"}
+
+	for name, filter := range map[string]Filter{
+		"all":          {},
+		"user":         {UserID: "bob"},
+		"status":       {Status: model.TransactionStatusPending},
+		"type":         {Type: model.TransactionTypeDeposit},
+		"period":       {From: &from, To: &to},
+		"currency":     {Currency: "EUR"},
+		"amount range": {MinAmount: &minAmount, MaxAmount: &maxAmount},
+		"scope": {Scope: &Scope{Rules: []ScopeRule{
+			{UserIDs: []string{"alice"}},
+			{IDs: []string{"txn_01", "txn_02"}, Statuses: []string{model.TransactionStatusCompleted}},
+		}}},
+		"empty scope": {Scope: &Scope{}},
+	} {
+		for _, sort := range []string{"", SortCreatedAt, "-" + SortAmount, SortAmount} {
+			opts := ListOptions{Limit: 4, Sort: sort, Filter: filter}
+			postgres := listAll(t, repos["postgres"], opts)
+			memory := listAll(t, repos["memory"], opts)
+			if !reflect.DeepEqual(postgres, memory) {
+				t.Errorf("%s, sort %q:\npostgres %v\nmemory   %v", name, sort, postgres, memory)
+			}
+		}
+	}
+}
+
+func TestReportImplementationsAgree(t *testing.T) {
+	repos := newTransactionRepositories(t)
+	ctx := context.Background()
+
+	// Period grouping uses date_trunc, which SQLite does not have
+	for _, opts := range []ReportOptions{
+		{},
+		{GroupBy: []string{GroupStatus}},
+		{GroupBy: []string{GroupType, GroupUser}},
+		{GroupBy: []string{GroupStatus, GroupType, GroupUser}, Filter: Filter{Currency: "USD"}},
+	} {
+		postgres, err := repos["postgres"].Report(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		memory, err := repos["memory"].Report(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(postgres, memory) {
+			t.Errorf("group by %v:\npostgres %+v\nmemory   %+v", opts.GroupBy, postgres, memory)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// LoginFailureRepository keeps failed login counters
+type LoginFailureRepository interface {
+	Get(ctx context.Context, key string) (*model.LoginFailure, error)
+	// Reserve replaces the counter prev (nil: no counter yet) with next,
+	// only if nobody changed it since it was read. It reports whether it did.
+	Reserve(ctx context.Context, prev, next *model.LoginFailure) (bool, error)
+	// Refund takes back a reservation: the counter goes back to prev if it
+	// is still reserved, otherwise it loses one failure
+	Refund(ctx context.Context, reserved, prev *model.LoginFailure) error
+	Reset(ctx context.Context, key string) error
+	// Prune removes counters whose last failure is before cutoff
+	Prune(ctx context.Context, cutoff time.Time) (int64, error)
+}
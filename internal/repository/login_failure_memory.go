@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+type memoryLoginFailureRepository struct {
+	mu       sync.Mutex
+	failures map[string]model.LoginFailure
+}
+
+// NewMemoryLoginFailureRepository creates an in-memory LoginFailureRepository.
+// Counters are per process, so each instance limits logins on its own.
+func NewMemoryLoginFailureRepository() LoginFailureRepository {
+	return &memoryLoginFailureRepository{failures: map[string]model.LoginFailure{}}
+}
+
+func (r *memoryLoginFailureRepository) Get(ctx context.Context, key string) (*model.LoginFailure, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failure, ok := r.failures[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &failure, nil
+}
+
+func (r *memoryLoginFailureRepository) Reserve(ctx context.Context, prev, next *model.LoginFailure) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.failures[next.Key]
+	if !sameFailure(current, ok, prev) {
+		return false, nil
+	}
+	r.failures[next.Key] = *next
+	return true, nil
+}
+
+func (r *memoryLoginFailureRepository) Refund(ctx context.Context, reserved, prev *model.LoginFailure) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.failures[reserved.Key]
+	switch {
+	case sameFailure(current, ok, reserved) && prev == nil:
+		delete(r.failures, reserved.Key)
+	case sameFailure(current, ok, reserved):
+		r.failures[reserved.Key] = *prev
+	case ok && current.Failures > 0:
+		current.Failures--
+		r.failures[reserved.Key] = current
+	}
+	return nil
+}
+
+// sameFailure reports whether the stored counter (ok: it exists) is want
+func sameFailure(current model.LoginFailure, ok bool, want *model.LoginFailure) bool {
+	if want == nil {
+		return !ok
+	}
+	return ok && current.Failures == want.Failures && current.LastFailure.Equal(want.LastFailure)
+}
+
+func (r *memoryLoginFailureRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, key)
+	return nil
+}
+
+func (r *memoryLoginFailureRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, failure := range r.failures {
+		if failure.LastFailure.Before(cutoff) {
+			delete(r.failures, key)
+			n++
+		}
+	}
+	return n, nil
+}
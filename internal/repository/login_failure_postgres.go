@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresLoginFailureRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresLoginFailureRepository creates a LoginFailureRepository backed by Postgres
+func NewPostgresLoginFailureRepository(db *gorm.DB) LoginFailureRepository {
+	return &postgresLoginFailureRepository{db: db}
+}
+
+func (r *postgresLoginFailureRepository) Get(ctx context.Context, key string) (*model.LoginFailure, error) {
+	var failure model.LoginFailure
+	if err := conn(ctx, r.db).First(&failure, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &failure, nil
+}
+
+func (r *postgresLoginFailureRepository) Reserve(ctx context.Context, prev, next *model.LoginFailure) (bool, error) {
+	// Compare-and-swap, so concurrent attempts from several instances
+	// cannot all pass on the same counter
+	if prev == nil {
+		result := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(next)
+		return result.RowsAffected == 1, result.Error
+	}
+	result := conn(ctx, r.db).Model(&model.LoginFailure{}).
+		Where("key = ? AND failures = ? AND last_failure = ?", prev.Key, prev.Failures, prev.LastFailure).
+		Updates(map[string]interface{}{"failures": next.Failures, "last_failure": next.LastFailure})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *postgresLoginFailureRepository) Refund(ctx context.Context, reserved, prev *model.LoginFailure) error {
+	restore := conn(ctx, r.db).Where("key = ? AND failures = ? AND last_failure = ?", reserved.Key, reserved.Failures, reserved.LastFailure)
+	var result *gorm.DB
+	if prev == nil {
+		result = restore.Delete(&model.LoginFailure{})
+	} else {
+		result = restore.Model(&model.LoginFailure{}).
+			Updates(map[string]interface{}{"failures": prev.Failures, "last_failure": prev.LastFailure})
+	}
+	if result.Error != nil || result.RowsAffected == 1 {
+		return result.Error
+	}
+	// Later attempts changed the counter; only take this one back
+	return conn(ctx, r.db).Model(&model.LoginFailure{}).
+		Where("key = ? AND failures > 0", reserved.Key).
+		Update("failures", gorm.Expr("failures - 1")).Error
+}
+
+func (r *postgresLoginFailureRepository) Reset(ctx context.Context, key string) error {
+	return conn(ctx, r.db).Delete(&model.LoginFailure{}, "key = ?", key).Error
+}
+
+func (r *postgresLoginFailureRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := conn(ctx, r.db).Delete(&model.LoginFailure{}, "last_failure < ?", cutoff)
+	return result.RowsAffected, result.Error
+}
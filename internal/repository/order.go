@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// OrderRepository stores orders
+type OrderRepository interface {
+	List(ctx context.Context, opts ListOptions) (*Page[model.Order], error)
+	Get(ctx context.Context, id string) (*model.Order, error)
+	// Lock loads an order and locks it until the surrounding transaction ends
+	Lock(ctx context.Context, id string) (*model.Order, error)
+	Create(ctx context.Context, order *model.Order) error
+	// UpdateStatus moves an order from one status to another and records
+	// the change in the status history. It returns ErrConflict if the
+	// order is no longer in the from status.
+	UpdateStatus(ctx context.Context, id, from, to, changedBy string) (*model.Order, error)
+	ListStatusHistory(ctx context.Context, orderID string) ([]model.OrderStatusHistory, error)
+	// Report returns counts and order totals grouped by opts
+	Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error)
+}
+
+var orderSchema = listSchema[model.Order]{
+	resource:     "orders",
+	amountPrefix: "total_",
+	id:           func(o model.Order) string { return o.ID },
+	userID:       func(o model.Order) string { return o.UserID },
+	status:       func(o model.Order) string { return o.Status },
+	typ:          func(o model.Order) string { return "" },
+	createdAt:    func(o model.Order) time.Time { return o.CreatedAt },
+	amount:       func(o model.Order) money.Money { return o.Total },
+}
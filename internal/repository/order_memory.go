@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+type memoryOrderRepository struct {
+	mu      sync.RWMutex
+	orders  []model.Order
+	history []model.OrderStatusHistory
+}
+
+// NewMemoryOrderRepository creates an in-memory OrderRepository for tests
+func NewMemoryOrderRepository(orders ...model.Order) OrderRepository {
+	return &memoryOrderRepository{orders: append([]model.Order(nil), orders...)}
+}
+
+func (r *memoryOrderRepository) List(ctx context.Context, opts ListOptions) (*Page[model.Order], error) {
+	n, err := orderSchema.normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return orderSchema.page(n, orderSchema.filter(r.orders, n)), nil
+}
+
+func (r *memoryOrderRepository) Get(ctx context.Context, id string) (*model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, order := range r.orders {
+		if order.ID == id {
+			return &order, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOrderRepository) Lock(ctx context.Context, id string) (*model.Order, error) {
+	return r.Get(ctx, id)
+}
+
+func (r *memoryOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.orders {
+		if existing.ID == order.ID {
+			return fmt.Errorf("order %s already exists", order.ID)
+		}
+	}
+	r.orders = append(r.orders, *order)
+	return nil
+}
+
+func (r *memoryOrderRepository) UpdateStatus(ctx context.Context, id, from, to, changedBy string) (*model.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.orders {
+		if r.orders[i].ID == id {
+			if r.orders[i].Status != from {
+				return nil, ErrConflict
+			}
+			r.orders[i].Status = to
+			r.history = append(r.history, model.OrderStatusHistory{
+				ID:         uint(len(r.history) + 1),
+				OrderID:    id,
+				FromStatus: from,
+				ToStatus:   to,
+				ChangedBy:  changedBy,
+				ChangedAt:  time.Now(),
+			})
+			order := r.orders[i]
+			return &order, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOrderRepository) ListStatusHistory(ctx context.Context, orderID string) ([]model.OrderStatusHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := []model.OrderStatusHistory{}
+	for _, entry := range r.history {
+		if entry.OrderID == orderID {
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+func (r *memoryOrderRepository) Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return orderSchema.reportItems(r.orders, opts)
+}
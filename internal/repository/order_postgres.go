@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresOrderRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresOrderRepository creates an OrderRepository backed by Postgres
+func NewPostgresOrderRepository(db *gorm.DB) OrderRepository {
+	return &postgresOrderRepository{db: db}
+}
+
+func (r *postgresOrderRepository) List(ctx context.Context, opts ListOptions) (*Page[model.Order], error) {
+	n, err := orderSchema.normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []model.Order
+	if err := orderSchema.query(conn(ctx, r.db), n).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orderSchema.page(n, orders), nil
+}
+
+func (r *postgresOrderRepository) Get(ctx context.Context, id string) (*model.Order, error) {
+	var order model.Order
+	if err := conn(ctx, r.db).First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *postgresOrderRepository) Lock(ctx context.Context, id string) (*model.Order, error) {
+	var order model.Order
+	if err := conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *postgresOrderRepository) Create(ctx context.Context, order *model.Order) error {
+	return conn(ctx, r.db).Create(order).Error
+}
+
+func (r *postgresOrderRepository) UpdateStatus(ctx context.Context, id, from, to, changedBy string) (*model.Order, error) {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Order{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&model.Order{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrNotFound
+			}
+			return ErrConflict
+		}
+
+		return tx.Create(&model.OrderStatusHistory{
+			OrderID:    id,
+			FromStatus: from,
+			ToStatus:   to,
+			ChangedBy:  changedBy,
+			ChangedAt:  time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *postgresOrderRepository) ListStatusHistory(ctx context.Context, orderID string) ([]model.OrderStatusHistory, error) {
+	history := []model.OrderStatusHistory{}
+	if err := conn(ctx, r.db).Where("order_id = ?", orderID).Order("changed_at, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *postgresOrderRepository) Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error) {
+	return orderSchema.report(conn(ctx, r.db).Model(&model.Order{}), opts)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+var (
+	// ErrOutOfStock is returned when a product has fewer units in stock than requested
+	ErrOutOfStock = errors.New("insufficient stock")
+	// ErrDuplicateSKU is returned when creating a product with an existing SKU
+	ErrDuplicateSKU = errors.New("sku already exists")
+)
+
+// ProductRepository stores the product catalog and its stock levels
+type ProductRepository interface {
+	// List returns products ordered by SKU; inactive products only if includeInactive
+	List(ctx context.Context, includeInactive bool) ([]model.Product, error)
+	Get(ctx context.Context, id string) (*model.Product, error)
+	Create(ctx context.Context, product *model.Product) error
+	// Update writes the fields set in update and returns the product. The
+	// SKU cannot change. It returns ErrOutOfStock if a stock adjustment
+	// would take the stock below zero.
+	Update(ctx context.Context, id string, update ProductUpdate) (*model.Product, error)
+	Delete(ctx context.Context, id string) error
+	// Reserve atomically takes quantity units of an active product out of
+	// stock and returns the product. It returns ErrNotFound for unknown or
+	// inactive SKUs and ErrOutOfStock if not enough units are left.
+	Reserve(ctx context.Context, sku string, quantity int) (*model.Product, error)
+	// Release puts quantity units of a reserved product back into stock
+	Release(ctx context.Context, sku string, quantity int) error
+}
+
+// ProductUpdate lists the product fields to change; nil fields are left
+// as they are, so concurrent reservations are not overwritten
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Price       *money.Money
+	// Stock replaces the stock level, e.g. after a stocktake
+	Stock *int
+	// StockDelta adds to the current stock, or takes from it if negative
+	StockDelta *int
+	Active     *bool
+}
+
+// Apply copies the fields set in u onto product
+func (u ProductUpdate) Apply(product *model.Product) {
+	if u.Name != nil {
+		product.Name = *u.Name
+	}
+	if u.Description != nil {
+		product.Description = *u.Description
+	}
+	if u.Price != nil {
+		product.Price = *u.Price
+	}
+	if u.Stock != nil {
+		product.Stock = *u.Stock
+	}
+	if u.StockDelta != nil {
+		product.Stock += *u.StockDelta
+	}
+	if u.Active != nil {
+		product.Active = *u.Active
+	}
+}
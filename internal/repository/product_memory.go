@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+type memoryProductRepository struct {
+	mu       sync.RWMutex
+	products []model.Product
+}
+
+// NewMemoryProductRepository creates an in-memory ProductRepository for tests
+func NewMemoryProductRepository(products ...model.Product) ProductRepository {
+	return &memoryProductRepository{products: products}
+}
+
+func (r *memoryProductRepository) List(ctx context.Context, includeInactive bool) ([]model.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := []model.Product{}
+	for _, product := range r.products {
+		if includeInactive || product.Active {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].SKU < products[j].SKU })
+	return products, nil
+}
+
+func (r *memoryProductRepository) Get(ctx context.Context, id string) (*model.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, product := range r.products {
+		if product.ID == id {
+			return &product, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProductRepository) Create(ctx context.Context, product *model.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.products {
+		if existing.SKU == product.SKU {
+			return ErrDuplicateSKU
+		}
+	}
+	r.products = append(r.products, *product)
+	return nil
+}
+
+func (r *memoryProductRepository) Update(ctx context.Context, id string, update ProductUpdate) (*model.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.products {
+		if r.products[i].ID == id {
+			p := &r.products[i]
+			if update.StockDelta != nil && p.Stock+*update.StockDelta < 0 {
+				return nil, ErrOutOfStock
+			}
+			update.Apply(p)
+			p.UpdatedAt = time.Now()
+			product := *p
+			return &product, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProductRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.products {
+		if r.products[i].ID == id {
+			r.products = append(r.products[:i], r.products[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryProductRepository) Reserve(ctx context.Context, sku string, quantity int) (*model.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.products {
+		if r.products[i].SKU == sku && r.products[i].Active {
+			if r.products[i].Stock < quantity {
+				return nil, ErrOutOfStock
+			}
+			r.products[i].Stock -= quantity
+			product := r.products[i]
+			return &product, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProductRepository) Release(ctx context.Context, sku string, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.products {
+		if r.products[i].SKU == sku {
+			r.products[i].Stock += quantity
+		}
+	}
+	return nil
+}
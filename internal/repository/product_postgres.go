@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresProductRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresProductRepository creates a ProductRepository backed by Postgres
+func NewPostgresProductRepository(db *gorm.DB) ProductRepository {
+	return &postgresProductRepository{db: db}
+}
+
+func (r *postgresProductRepository) List(ctx context.Context, includeInactive bool) ([]model.Product, error) {
+	db := conn(ctx, r.db)
+	if !includeInactive {
+		db = db.Where("active = ?", true)
+	}
+
+	products := []model.Product{}
+	if err := db.Order("sku").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *postgresProductRepository) Get(ctx context.Context, id string) (*model.Product, error) {
+	var product model.Product
+	if err := conn(ctx, r.db).First(&product, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *postgresProductRepository) Create(ctx context.Context, product *model.Product) error {
+	result := conn(ctx, r.db).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "sku"}}, DoNothing: true}).Create(product)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicateSKU
+	}
+	return nil
+}
+
+func (r *postgresProductRepository) Update(ctx context.Context, id string, update ProductUpdate) (*model.Product, error) {
+	// Only the columns in the update are written, and stock adjustments are
+	// relative, so a reservation committed meanwhile is kept.
+	columns := map[string]interface{}{"updated_at": time.Now()}
+	db := conn(ctx, r.db).Where("id = ?", id)
+	if update.Name != nil {
+		columns["name"] = *update.Name
+	}
+	if update.Description != nil {
+		columns["description"] = *update.Description
+	}
+	if update.Price != nil {
+		columns["price_minor"] = update.Price.Minor
+		columns["price_currency"] = update.Price.Currency
+	}
+	if update.Stock != nil {
+		columns["stock"] = *update.Stock
+	}
+	if update.StockDelta != nil {
+		columns["stock"] = gorm.Expr("stock + ?", *update.StockDelta)
+		db = db.Where("stock + ? >= 0", *update.StockDelta)
+	}
+	if update.Active != nil {
+		columns["active"] = *update.Active
+	}
+
+	var products []model.Product
+	result := db.Model(&products).Clauses(clause.Returning{}).UpdateColumns(columns)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 && len(products) == 1 {
+		return &products[0], nil
+	}
+
+	if _, err := r.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrOutOfStock
+}
+
+func (r *postgresProductRepository) Delete(ctx context.Context, id string) error {
+	result := conn(ctx, r.db).Delete(&model.Product{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresProductRepository) Reserve(ctx context.Context, sku string, quantity int) (*model.Product, error) {
+	// A single conditional UPDATE decrements stock atomically; concurrent
+	// reservations cannot both take the last units.
+	var products []model.Product
+	result := conn(ctx, r.db).Model(&products).Clauses(clause.Returning{}).
+		Where("sku = ? AND active = ? AND stock >= ?", sku, true, quantity).
+		UpdateColumn("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 && len(products) == 1 {
+		return &products[0], nil
+	}
+
+	var product model.Product
+	if err := conn(ctx, r.db).First(&product, "sku = ? AND active = ?", sku, true).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return nil, ErrOutOfStock
+}
+
+func (r *postgresProductRepository) Release(ctx context.Context, sku string, quantity int) error {
+	// Products deleted since the order was placed have nothing to restock
+	return conn(ctx, r.db).Model(&model.Product{}).Where("sku = ?", sku).
+		UpdateColumn("stock", gorm.Expr("stock + ?", quantity)).Error
+}
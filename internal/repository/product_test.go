@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+func newProductRepository(t *testing.T) ProductRepository {
+	t.Helper()
+	return NewPostgresProductRepository(newTestDB(t, &model.Product{}))
+}
+
+func TestProductUpdateKeepsReservations(t *testing.T) {
+	ctx := context.Background()
+	products := newProductRepository(t)
+	now := time.Now()
+	product := &model.Product{ID: "prod_1", SKU: "LAPTOP", Name: "Laptop", Price: money.Money{Minor: 100000, Currency: "USD"}, Stock: 10, Active: true, CreatedAt: now, UpdatedAt: now}
+	if err := products.Create(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reservation commits between the admin reading and updating the product
+	if _, err := products.Reserve(ctx, "LAPTOP", 3); err != nil {
+		t.Fatal(err)
+	}
+	name := "Laptop Pro"
+	updated, err := products.Update(ctx, product.ID, ProductUpdate{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != name || updated.Stock != 7 {
+		t.Errorf("after renaming: name %q, stock %d; want %q and 7", updated.Name, updated.Stock, name)
+	}
+
+	delta := 5
+	if updated, err = products.Update(ctx, product.ID, ProductUpdate{StockDelta: &delta}); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Stock != 12 {
+		t.Errorf("after adding 5: stock %d, want 12", updated.Stock)
+	}
+
+	delta = -13
+	if _, err := products.Update(ctx, product.ID, ProductUpdate{StockDelta: &delta}); !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("taking 13 of 12: err = %v, want ErrOutOfStock", err)
+	}
+	if _, err := products.Update(ctx, "prod_missing", ProductUpdate{Name: &name}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown product: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"casdoor-casbin-openbao/internal/money"
+	"gorm.io/gorm"
+)
+
+// Report periods
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week" // ISO weeks, starting on Monday
+	PeriodMonth = "month"
+)
+
+// Report dimensions
+const (
+	GroupStatus = "status"
+	GroupType   = "type"
+	GroupUser   = "user"
+)
+
+// ReportOptions controls grouping and filtering of aggregate reports.
+// Rows are always grouped by currency as well, so totals never mix
+// currencies. Periods are computed in UTC.
+type ReportOptions struct {
+	Period  string
+	GroupBy []string
+	Filter  Filter
+}
+
+// ReportRow is one group of a report. Fields of dimensions that were not
+// grouped by are empty.
+type ReportRow struct {
+	Period *time.Time  `json:"period,omitempty"`
+	Status string      `json:"status,omitempty"`
+	Type   string      `json:"type,omitempty"`
+	UserID string      `json:"user_id,omitempty"`
+	Count  int64       `json:"count"`
+	Total  money.Money `json:"total"`
+}
+
+// reportGroups is the validated form of ReportOptions grouping
+type reportGroups struct {
+	period string
+	status bool
+	typ    bool
+	user   bool
+}
+
+func (s listSchema[T]) normalizeReport(opts ReportOptions) (*reportGroups, error) {
+	g := &reportGroups{period: opts.Period}
+	switch opts.Period {
+	case "", PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		return nil, fmt.Errorf("%w: unknown period %q", ErrInvalidListOptions, opts.Period)
+	}
+
+	for _, dim := range opts.GroupBy {
+		switch dim {
+		case GroupStatus:
+			g.status = true
+		case GroupType:
+			if !s.hasType {
+				return nil, fmt.Errorf("%w: %s cannot be grouped by type", ErrInvalidListOptions, s.resource)
+			}
+			g.typ = true
+		case GroupUser:
+			g.user = true
+		default:
+			return nil, fmt.Errorf("%w: cannot group by %q", ErrInvalidListOptions, dim)
+		}
+	}
+
+	if opts.Filter.Type != "" && !s.hasType {
+		return nil, fmt.Errorf("%w: %s cannot be filtered by type", ErrInvalidListOptions, s.resource)
+	}
+	return g, nil
+}
+
+// reportResult is the scan target of the report query
+type reportResult struct {
+	Period   *time.Time
+	Status   string
+	Type     string
+	UserID   string
+	Currency string
+	Count    int64
+	Sum      int64
+}
+
+// report aggregates the rows of a GORM model query
+func (s listSchema[T]) report(db *gorm.DB, opts ReportOptions) ([]ReportRow, error) {
+	g, err := s.normalizeReport(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns, groups []string
+	if g.period != "" {
+		columns = append(columns, fmt.Sprintf("date_trunc('%s', created_at AT TIME ZONE 'UTC') AS period", g.period))
+		groups = append(groups, "period")
+	}
+	for _, dim := range []struct {
+		enabled bool
+		column  string
+	}{{g.status, "status"}, {g.typ, "type"}, {g.user, "user_id"}} {
+		if dim.enabled {
+			columns = append(columns, dim.column)
+			groups = append(groups, dim.column)
+		}
+	}
+	currency := s.amountPrefix + "currency"
+	columns = append(columns, currency+" AS currency", "COUNT(*) AS count", "COALESCE(SUM("+s.amountPrefix+"minor), 0) AS sum")
+	groups = append(groups, currency)
+
+	var results []reportResult
+	err = s.where(db, opts.Filter).
+		Select(strings.Join(columns, ", ")).
+		Group(strings.Join(groups, ", ")).
+		Order(strings.Join(groups, ", ")).
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ReportRow, 0, len(results))
+	for _, r := range results {
+		if r.Period != nil {
+			period := r.Period.UTC()
+			r.Period = &period
+		}
+		rows = append(rows, ReportRow{
+			Period: r.Period,
+			Status: r.Status,
+			Type:   r.Type,
+			UserID: r.UserID,
+			Count:  r.Count,
+			Total:  money.Money{Minor: r.Sum, Currency: r.Currency},
+		})
+	}
+	return rows, nil
+}
+
+// reportItems applies the same aggregation as report to an in-memory slice
+func (s listSchema[T]) reportItems(items []T, opts ReportOptions) ([]ReportRow, error) {
+	g, err := s.normalizeReport(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		period                        time.Time
+		status, typ, userID, currency string
+	}
+	groups := map[key]*ReportRow{}
+	for _, item := range items {
+		if !s.matches(item, opts.Filter) {
+			continue
+		}
+
+		k := key{currency: s.amount(item).Currency}
+		if g.period != "" {
+			k.period = truncatePeriod(s.createdAt(item), g.period)
+		}
+		if g.status {
+			k.status = s.status(item)
+		}
+		if g.typ {
+			k.typ = s.typ(item)
+		}
+		if g.user {
+			k.userID = s.userID(item)
+		}
+
+		row, ok := groups[k]
+		if !ok {
+			row = &ReportRow{Status: k.status, Type: k.typ, UserID: k.userID, Total: money.Zero(k.currency)}
+			if g.period != "" {
+				period := k.period
+				row.Period = &period
+			}
+			groups[k] = row
+		}
+		total, err := row.Total.Add(s.amount(item))
+		if err != nil {
+			return nil, err
+		}
+		row.Count++
+		row.Total = total
+	}
+
+	rows := make([]ReportRow, 0, len(groups))
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Period != nil && !a.Period.Equal(*b.Period) {
+			return a.Period.Before(*b.Period)
+		}
+		for _, pair := range [][2]string{{a.Status, b.Status}, {a.Type, b.Type}, {a.UserID, b.UserID}} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return a.Total.Currency < b.Total.Currency
+	})
+	return rows, nil
+}
+
+// truncatePeriod returns the start of the UTC day, ISO week or month containing t
+func truncatePeriod(t time.Time, period string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodWeek:
+		// time.Weekday starts on Sunday; ISO weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
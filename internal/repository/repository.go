@@ -0,0 +1,15 @@
+// Package repository provides persistence for orders and transactions.
+// Each repository has a Postgres implementation backed by GORM. Orders,
+// transactions, products, accounts and idempotency keys also have an
+// in-memory implementation for tests; list_test.go checks that it lists
+// and reports like the SQL.
+package repository
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when a record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a record changed concurrently
+	ErrConflict = errors.New("record was modified concurrently")
+)
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+// ErrDuplicateServiceAccount is returned when the name or client ID of a
+// new service account is taken
+var ErrDuplicateServiceAccount = errors.New("service account name or client ID already exists")
+
+// ServiceAccountRepository stores service accounts
+type ServiceAccountRepository interface {
+	// List returns every account ordered by name
+	List(ctx context.Context) ([]model.ServiceAccount, error)
+	Get(ctx context.Context, name string) (*model.ServiceAccount, error)
+	GetByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error)
+	Create(ctx context.Context, account *model.ServiceAccount) error
+	// Update saves description and disabled
+	Update(ctx context.Context, account *model.ServiceAccount) error
+	Delete(ctx context.Context, name string) error
+}
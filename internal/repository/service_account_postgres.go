@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postgresServiceAccountRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresServiceAccountRepository creates a ServiceAccountRepository backed by Postgres
+func NewPostgresServiceAccountRepository(db *gorm.DB) ServiceAccountRepository {
+	return &postgresServiceAccountRepository{db: db}
+}
+
+func (r *postgresServiceAccountRepository) List(ctx context.Context) ([]model.ServiceAccount, error) {
+	accounts := []model.ServiceAccount{}
+	if err := conn(ctx, r.db).Order("name").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *postgresServiceAccountRepository) Get(ctx context.Context, name string) (*model.ServiceAccount, error) {
+	return r.first(ctx, "name = ?", name)
+}
+
+func (r *postgresServiceAccountRepository) GetByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error) {
+	return r.first(ctx, "client_id = ?", clientID)
+}
+
+func (r *postgresServiceAccountRepository) first(ctx context.Context, query string, arg string) (*model.ServiceAccount, error) {
+	var account model.ServiceAccount
+	if err := conn(ctx, r.db).First(&account, query, arg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *postgresServiceAccountRepository) Create(ctx context.Context, account *model.ServiceAccount) error {
+	result := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).Create(account)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicateServiceAccount
+	}
+	return nil
+}
+
+func (r *postgresServiceAccountRepository) Update(ctx context.Context, account *model.ServiceAccount) error {
+	account.UpdatedAt = time.Now()
+	result := conn(ctx, r.db).Model(&model.ServiceAccount{}).Where("name = ?", account.Name).Updates(map[string]interface{}{
+		"description": account.Description,
+		"disabled":    account.Disabled,
+		"updated_at":  account.UpdatedAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresServiceAccountRepository) Delete(ctx context.Context, name string) error {
+	result := conn(ctx, r.db).Delete(&model.ServiceAccount{}, "name = ?", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"casdoor-casbin-openbao/internal/money"
+)
+
+// TransactionRepository stores transactions
+type TransactionRepository interface {
+	List(ctx context.Context, opts ListOptions) (*Page[model.Transaction], error)
+	Get(ctx context.Context, id string) (*model.Transaction, error)
+	ListByOrder(ctx context.Context, orderID string) ([]model.Transaction, error)
+	Create(ctx context.Context, txn *model.Transaction) error
+	// Review moves a transaction from one status to another and records
+	// the reviewer. It returns ErrConflict if the transaction is no longer
+	// in the from status.
+	Review(ctx context.Context, id, from, to, reviewer, note string) (*model.Transaction, error)
+	// Report returns counts and amount totals grouped by opts
+	Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error)
+}
+
+var transactionSchema = listSchema[model.Transaction]{
+	resource:     "transactions",
+	amountPrefix: "amount_",
+	hasType:      true,
+	id:           func(t model.Transaction) string { return t.ID },
+	userID:       func(t model.Transaction) string { return t.UserID },
+	status:       func(t model.Transaction) string { return t.Status },
+	typ:          func(t model.Transaction) string { return t.Type },
+	createdAt:    func(t model.Transaction) time.Time { return t.CreatedAt },
+	amount:       func(t model.Transaction) money.Money { return t.Amount },
+}
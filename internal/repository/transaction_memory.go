@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+)
+
+type memoryTransactionRepository struct {
+	mu   sync.RWMutex
+	txns []model.Transaction
+}
+
+// NewMemoryTransactionRepository creates an in-memory TransactionRepository for tests
+func NewMemoryTransactionRepository(txns ...model.Transaction) TransactionRepository {
+	return &memoryTransactionRepository{txns: append([]model.Transaction(nil), txns...)}
+}
+
+func (r *memoryTransactionRepository) List(ctx context.Context, opts ListOptions) (*Page[model.Transaction], error) {
+	n, err := transactionSchema.normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return transactionSchema.page(n, transactionSchema.filter(r.txns, n)), nil
+}
+
+func (r *memoryTransactionRepository) Get(ctx context.Context, id string) (*model.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, txn := range r.txns {
+		if txn.ID == id {
+			return &txn, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTransactionRepository) ListByOrder(ctx context.Context, orderID string) ([]model.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	txns := []model.Transaction{}
+	for _, txn := range r.txns {
+		if txn.OrderID == orderID {
+			txns = append(txns, txn)
+		}
+	}
+	return txns, nil
+}
+
+func (r *memoryTransactionRepository) Create(ctx context.Context, txn *model.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.txns {
+		if existing.ID == txn.ID {
+			return fmt.Errorf("transaction %s already exists", txn.ID)
+		}
+	}
+	r.txns = append(r.txns, *txn)
+	return nil
+}
+
+func (r *memoryTransactionRepository) Review(ctx context.Context, id, from, to, reviewer, note string) (*model.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.txns {
+		if r.txns[i].ID == id {
+			if r.txns[i].Status != from {
+				return nil, ErrConflict
+			}
+			now := time.Now()
+			r.txns[i].Status = to
+			r.txns[i].ReviewedBy = reviewer
+			r.txns[i].ReviewedAt = &now
+			r.txns[i].ReviewNote = note
+			txn := r.txns[i]
+			return &txn, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTransactionRepository) Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return transactionSchema.reportItems(r.txns, opts)
+}
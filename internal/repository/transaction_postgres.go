@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"casdoor-casbin-openbao/internal/model"
+	"gorm.io/gorm"
+)
+
+type postgresTransactionRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresTransactionRepository creates a TransactionRepository backed by Postgres
+func NewPostgresTransactionRepository(db *gorm.DB) TransactionRepository {
+	return &postgresTransactionRepository{db: db}
+}
+
+func (r *postgresTransactionRepository) List(ctx context.Context, opts ListOptions) (*Page[model.Transaction], error) {
+	n, err := transactionSchema.normalize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var txns []model.Transaction
+	if err := transactionSchema.query(conn(ctx, r.db), n).Find(&txns).Error; err != nil {
+		return nil, err
+	}
+	return transactionSchema.page(n, txns), nil
+}
+
+func (r *postgresTransactionRepository) Get(ctx context.Context, id string) (*model.Transaction, error) {
+	var txn model.Transaction
+	if err := conn(ctx, r.db).First(&txn, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+func (r *postgresTransactionRepository) ListByOrder(ctx context.Context, orderID string) ([]model.Transaction, error) {
+	txns := []model.Transaction{}
+	if err := conn(ctx, r.db).Where("order_id = ?", orderID).Order("created_at, id").Find(&txns).Error; err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+func (r *postgresTransactionRepository) Create(ctx context.Context, txn *model.Transaction) error {
+	return conn(ctx, r.db).Create(txn).Error
+}
+
+func (r *postgresTransactionRepository) Review(ctx context.Context, id, from, to, reviewer, note string) (*model.Transaction, error) {
+	result := conn(ctx, r.db).Model(&model.Transaction{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]interface{}{
+			"status":      to,
+			"reviewed_by": reviewer,
+			"reviewed_at": time.Now(),
+			"review_note": note,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrConflict
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *postgresTransactionRepository) Report(ctx context.Context, opts ReportOptions) ([]ReportRow, error) {
+	return transactionSchema.report(conn(ctx, r.db).Model(&model.Transaction{}), opts)
+}
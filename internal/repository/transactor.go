@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs a function inside a database transaction. Repositories
+// called with the context passed to fn join that transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type postgresTransactor struct {
+	db *gorm.DB
+}
+
+// NewPostgresTransactor creates a Transactor backed by GORM transactions
+func NewPostgresTransactor(db *gorm.DB) Transactor {
+	return &postgresTransactor{db: db}
+}
+
+func (t *postgresTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction carried by ctx, or db bound to ctx
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+type memoryTransactor struct{}
+
+// NewMemoryTransactor creates a Transactor for the in-memory repositories.
+// It runs fn directly: the in-memory repositories validate before they
+// mutate, but a failing fn does not roll back earlier writes.
+func NewMemoryTransactor() Transactor {
+	return memoryTransactor{}
+}
+
+func (memoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,90 @@
+// Package tabular reads and writes spreadsheet data as CSV or XLSX.
+//
+// Writers stream rows as they are written, so large exports never have to
+// fit in memory. CSV cells that a spreadsheet would evaluate as a formula
+// are escaped with a leading apostrophe on write and unescaped on read.
+package tabular
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnsupportedFormat is returned for formats other than csv and xlsx
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// Writer writes rows of cells. Flush pushes buffered rows to the
+// underlying writer; Close must be called to finish the file.
+type Writer interface {
+	Write(row []string) error
+	Flush() error
+	Close() error
+}
+
+// ContentType returns the MIME type of format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewWriter returns a streaming Writer for format
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// ReadAll reads every row of a CSV or XLSX file. For XLSX only the first
+// worksheet is read.
+func ReadAll(data []byte, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		rows, err := readCSV(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			for i := range row {
+				row[i] = unescapeFormula(row[i])
+			}
+		}
+		return rows, nil
+	case FormatXLSX:
+		return readXLSX(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// escapeFormula prefixes cells that start like a formula with an apostrophe.
+// Plain numbers such as "-12.50" are left alone.
+func escapeFormula(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+// unescapeFormula reverses escapeFormula
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
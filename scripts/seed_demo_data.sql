@@ -0,0 +1,21 @@
+-- Demo products and orders
+-- Run this after the server has started once (tables are created by migrations)
+-- Transactions are not seeded: create them through POST /api/transactions so
+-- they are posted to the ledger and account balances stay consistent.
+-- Seeded orders have no payment transaction, so cancelling or returning them
+-- issues no refund; orders created through POST /api/orders are paid.
+-- Amounts are stored in minor units (cents) with an ISO-4217 currency code.
+
+INSERT INTO products (id, sku, name, description, price_minor, price_currency, stock, active, created_at, updated_at) VALUES
+('prod_001', 'LAPTOP-PRO', 'Laptop Pro', '14-inch laptop', 129999, 'USD', 10, true, NOW(), NOW()),
+('prod_002', 'WIRELESS-MOUSE', 'Wireless Mouse', '', 2999, 'USD', 100, true, NOW(), NOW()),
+('prod_003', 'USB-CABLE', 'USB Cable', '', 999, 'USD', 250, true, NOW(), NOW()),
+('prod_004', 'DEMO-PRODUCT', 'Demo Product', 'Used by the demo page', 2599, 'USD', 1000, true, NOW(), NOW()),
+('prod_005', 'PREMIUM-SERVICE', 'Premium Service Package', 'Used by the orders page', 29999, 'USD', 1000, true, NOW(), NOW())
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO orders (id, user_id, sku, product_name, quantity, price_minor, price_currency, total_minor, total_currency, status, created_at, created_by) VALUES
+('ord_001', 'admin', 'LAPTOP-PRO', 'Laptop Pro', 1, 129999, 'USD', 129999, 'USD', 'delivered', NOW() - INTERVAL '48 hours', 'admin'),
+('ord_002', 'hihi', 'WIRELESS-MOUSE', 'Wireless Mouse', 2, 2999, 'USD', 5998, 'USD', 'processing', NOW() - INTERVAL '6 hours', 'hihi'),
+('ord_003', 'testuser', 'USB-CABLE', 'USB Cable', 3, 999, 'USD', 2997, 'USD', 'shipped', NOW() - INTERVAL '12 hours', 'testuser')
+ON CONFLICT (id) DO NOTHING;
@@ -2,18 +2,23 @@
 
 # Setup Group-Based Policies for ANZ Operations Portal
 # Usage: ./setup_groups.sh
+# Set ADMIN_API_KEY (an admin's API key with the admin:* scope) to skip the login
 
 echo "🔐 Setting up group-based policies..."
 
 # Get admin token
 echo "Getting admin token..."
-ADMIN_TOKEN=$(curl -s -X POST "http://localhost:8000/api/login" \
-  -d '{"application":"app-built-in","username":"admin","password":"123456","type":"token"}' \
-  -H "Content-Type: application/json" | jq -r .data)
-
-if [ "$ADMIN_TOKEN" = "null" ] || [ -z "$ADMIN_TOKEN" ]; then
-    echo "❌ Failed to get admin token. Make sure Casdoor is running on port 8000"
-    exit 1
+if [ -n "$ADMIN_API_KEY" ]; then
+    ADMIN_TOKEN=$ADMIN_API_KEY
+else
+    ADMIN_TOKEN=$(curl -s -X POST "http://localhost:8000/api/login" \
+      -d '{"application":"app-built-in","username":"admin","password":"123456","type":"token"}' \
+      -H "Content-Type: application/json" | jq -r .data)
+
+    if [ "$ADMIN_TOKEN" = "null" ] || [ -z "$ADMIN_TOKEN" ]; then
+        echo "❌ Failed to get admin token. Make sure Casdoor is running on port 8000"
+        exit 1
+    fi
 fi
 
 echo "✅ Got admin token"
@@ -2,6 +2,7 @@
 
 # Test Group-Based Access Control
 # Usage: ./test_groups.sh [username] [group_name]
+# Set ADMIN_API_KEY (an admin's API key with the admin:* scope) to skip the login
 
 USERNAME=${1:-"testuser"}
 GROUP=${2:-"dashboard_group"}
@@ -9,13 +10,17 @@ GROUP=${2:-"dashboard_group"}
 echo "🧪 Testing group access for user: $USERNAME in group: $GROUP"
 
 # Get admin token
-ADMIN_TOKEN=$(curl -s -X POST "http://localhost:8000/api/login" \
-  -d '{"application":"app-built-in","username":"admin","password":"123456","type":"token"}' \
-  -H "Content-Type: application/json" | jq -r .data)
-
-if [ "$ADMIN_TOKEN" = "null" ] || [ -z "$ADMIN_TOKEN" ]; then
-    echo "❌ Failed to get admin token"
-    exit 1
+if [ -n "$ADMIN_API_KEY" ]; then
+    ADMIN_TOKEN=$ADMIN_API_KEY
+else
+    ADMIN_TOKEN=$(curl -s -X POST "http://localhost:8000/api/login" \
+      -d '{"application":"app-built-in","username":"admin","password":"123456","type":"token"}' \
+      -H "Content-Type: application/json" | jq -r .data)
+
+    if [ "$ADMIN_TOKEN" = "null" ] || [ -z "$ADMIN_TOKEN" ]; then
+        echo "❌ Failed to get admin token"
+        exit 1
+    fi
 fi
 
 # 1. Assign user to group
@@ -181,7 +181,7 @@ async function testUsers() {
 }
 
 async function testSecrets() {
-    await testEndpoint('/api/secrets', '🔐 Secrets (Cert Verification)');
+    await testEndpoint('/api/secrets', '🔐 My Secret Folders (OpenBao)');
 }
 
 // Transaction tests
@@ -190,7 +190,7 @@ async function testMyTransactions() {
 }
 
 async function testAllTransactions() {
-    await testEndpoint('/api/transactions', '📈 All Transactions (Admin Only)');
+    await testEndpoint('/api/transactions', '📈 Transactions (scoped by policy)');
 }
 
 async function testCreateTransaction() {
@@ -248,7 +248,7 @@ async function testMyOrders() {
 }
 
 async function testAllOrders() {
-    await testEndpoint('/api/orders', '📋 All Orders (Admin Only)');
+    await testEndpoint('/api/orders', '📋 Orders (scoped by policy)');
 }
 
 async function testCreateOrder() {
@@ -265,9 +265,8 @@ async function testCreateOrder() {
                 'Content-Type': 'application/json'
             },
             body: JSON.stringify({
-                product_name: 'Demo Product',
-                quantity: 2,
-                price: 25.99
+                sku: 'DEMO-PRODUCT',
+                quantity: 2
             })
         });
 